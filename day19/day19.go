@@ -3,11 +3,13 @@ package main
 import (
 	"bufio"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -16,35 +18,38 @@ import (
 )
 
 func main() {
+	overlap := flag.Int("overlap", MinOverlap, "number of beacons two scanners need to have in common")
+	tolerance := flag.Int("tolerance", 0, "maximal error of each coordinate of a beacon position")
+	ply := flag.String("ply", "", "write the map of beacons and scanners to this file, as PLY")
+	js := flag.String("json", "", "write the map of beacons and scanners to this file, as JSON")
+	flag.Parse()
+
 	log.SetFlags(log.Lshortfile)
+	o := Options{Overlap: *overlap, Tolerance: *tolerance}
 	scanners, err := Parse(os.Stdin)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	done := set.Make(scanners[0])
-	open := set.Make(scanners[1:]...)
-	for len(open) > 0 {
-		for s1 := range open {
-			for s2 := range done {
-				if !s1.Intersect(s2) {
-					continue
-				}
-				s1.Transform = s2.Transform.Compose(s1.Transform)
-				done.Add(s1)
-				open.Delete(s1)
-				break
-			}
-		}
+	tree, err := Align(scanners, o)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, e := range tree {
+		log.Printf("Aligned scanner %d to %d: %d beacons, residual %.3f", e.From, e.To, e.Fit.Matched, e.Fit.Residual)
+	}
+	for _, s := range scanners {
+		log.Printf("Scanner %d: %v via %s", s.N, s.Transform, FormatPath(s.Path))
 	}
 
-	beacons := make(set.Set[vec.V])
-	for s := range done {
-		for b := range s.Beacons {
-			beacons.Add(s.Transform.Apply(b))
-		}
+	bm := Reconstruct(scanners, o)
+	log.Printf("There are %d beacons in total", len(bm.Beacons))
+	if err := writeFile(*ply, bm.WritePLY); err != nil {
+		log.Fatal(err)
+	}
+	if err := writeFile(*js, bm.WriteJSON); err != nil {
+		log.Fatal(err)
 	}
-	log.Printf("There are %d beacons in total", len(beacons))
 
 	m := math.MinInt
 	for _, s1 := range scanners {
@@ -58,6 +63,22 @@ func main() {
 	log.Printf("Maximum distance between scanners is %d", m)
 }
 
+// writeFile calls write with the named file, if name is not empty.
+func writeFile(name string, write func(io.Writer) error) error {
+	if name == "" {
+		return nil
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func Parse(r io.Reader) ([]*Scanner, error) {
 	var (
 		beacons  = make(set.Set[vec.V])
@@ -100,32 +121,8 @@ func Parse(r io.Reader) ([]*Scanner, error) {
 	return scanners, nil
 }
 
-var Rotations = set.Make(
-	vec.Mat(1, 0, 0, 0, 0, -1, 0, 1, 0),
-	vec.Mat(0, 0, 1, 0, 1, 0, -1, 0, 0),
-	vec.Mat(0, -1, 0, 1, 0, 0, 0, 0, 1),
-	vec.Mat(1, 0, 0, 0, -1, 0, 0, 0, -1),
-	vec.Mat(0, 0, 1, 1, 0, 0, 0, 1, 0),
-	vec.Mat(0, -1, 0, 0, 0, -1, 1, 0, 0),
-	vec.Mat(0, 1, 0, 0, 0, -1, -1, 0, 0),
-	vec.Mat(-1, 0, 0, 0, 1, 0, 0, 0, -1),
-	vec.Mat(0, 0, -1, 0, -1, 0, -1, 0, 0),
-	vec.Mat(0, 1, 0, 1, 0, 0, 0, 0, -1),
-	vec.Mat(0, -1, 0, 0, 0, 1, -1, 0, 0),
-	vec.Mat(-1, 0, 0, 0, -1, 0, 0, 0, 1),
-	vec.Mat(-1, 0, 0, 0, 0, 1, 0, 1, 0),
-	vec.Mat(0, 0, 1, 0, -1, 0, 1, 0, 0),
-	vec.Mat(0, -1, 0, -1, 0, 0, 0, 0, -1),
-	vec.Mat(1, 0, 0, 0, 0, 1, 0, -1, 0),
-	vec.Mat(0, 0, -1, 1, 0, 0, 0, -1, 0),
-	vec.Mat(0, 1, 0, 0, 0, 1, 1, 0, 0),
-	vec.Mat(-1, 0, 0, 0, 0, -1, 0, -1, 0),
-	vec.Mat(0, 0, 1, -1, 0, 0, 0, -1, 0),
-	vec.Mat(0, 0, -1, 0, 1, 0, 1, 0, 0),
-	vec.Mat(0, 0, -1, -1, 0, 0, 0, 1, 0),
-	vec.Mat(0, 1, 0, -1, 0, 0, 0, 0, 1),
-	vec.Mat(1, 0, 0, 0, 1, 0, 0, 0, 1),
-)
+// Rotations are the possible orientations of a scanner.
+var Rotations = vec.Rotations()
 
 type Affine struct {
 	A vec.M
@@ -140,39 +137,227 @@ func (a Affine) Compose(b Affine) Affine {
 	return Affine{A: a.A.MulM(b.A), B: a.A.MulV(b.B).Add(a.B)}
 }
 
+// Inverse returns the inverse of a. a.A must be a rotation.
+func (a Affine) Inverse() Affine {
+	ai := a.A.Inverse()
+	return Affine{A: ai, B: ai.MulV(vec.Vec(0, 0, 0).Sub(a.B))}
+}
+
 func (a Affine) String() string {
 	return fmt.Sprintf("%v•v+%v", a.A, a.B)
 }
 
+// MinOverlap is the default number of beacons two scanners need to have in
+// common, to be aligned.
+const MinOverlap = 12
+
+// Options configure the alignment of scanners.
+type Options struct {
+	// Overlap is the number of beacons two scanners need to have in common.
+	// If it is 0, MinOverlap is used.
+	Overlap int
+	// Tolerance is the maximal error of each coordinate of a measured beacon
+	// position. If it is 0, the beacons of aligned scanners have to match
+	// exactly.
+	Tolerance int
+}
+
+func (o Options) overlap() int {
+	if o.Overlap <= 0 {
+		return MinOverlap
+	}
+	return o.Overlap
+}
+
 type Scanner struct {
 	N         int
 	Beacons   set.Set[vec.V]
 	Transform Affine
+	// Path are the scanners from N to 0, through which Transform was
+	// derived.
+	Path []int
+	// Fingerprint are the squared distances between pairs of beacons, in
+	// increasing order. They don't depend on the orientation of the scanner.
+	Fingerprint []int
 }
 
 func NewScanner(n int, beacons set.Set[vec.V]) *Scanner {
-	return &Scanner{
+	s := &Scanner{
 		N:         n,
 		Beacons:   beacons,
 		Transform: Affine{A: vec.ID()},
 	}
+	bs := sorted(beacons)
+	for i, v := range bs {
+		for _, w := range bs[i+1:] {
+			d := v.Sub(w)
+			s.Fingerprint = append(s.Fingerprint, d.Mul(d))
+		}
+	}
+	sort.Ints(s.Fingerprint)
+	return s
+}
+
+// MayOverlap returns whether s1 and s2 might have enough beacons in common. If
+// they do, they also have the distances between all pairs of them in common.
+func (s1 *Scanner) MayOverlap(s2 *Scanner, o Options) bool {
+	// Each coordinate of the difference of two measured positions is off by
+	// at most 2•o.Tolerance, so its length by at most √3 times that. Both
+	// scanners measure with that error.
+	slack := 4 * math.Sqrt(3) * float64(o.Tolerance)
+	f1, f2 := s1.Fingerprint, s2.Fingerprint
+	var n int
+	for i, j := 0, 0; i < len(f1) && j < len(f2); {
+		d1, d2 := f1[i], f2[j]
+		switch {
+		case d1 == d2 || math.Abs(math.Sqrt(float64(d1))-math.Sqrt(float64(d2))) <= slack:
+			n, i, j = n+1, i+1, j+1
+		case d1 < d2:
+			i++
+		default:
+			j++
+		}
+	}
+	k := o.overlap()
+	return n >= k*(k-1)/2
+}
+
+// Fit is an alignment of one scanner to another.
+type Fit struct {
+	// Transform maps positions relative to the first scanner to positions
+	// relative to the second.
+	Transform Affine
+	// Matched is the number of beacons seen by both scanners.
+	Matched int
+	// Residual is the root mean square distance between the positions of the
+	// matched beacons, after alignment. It is 0 if o.Tolerance is 0.
+	Residual float64
 }
 
-func (s1 *Scanner) Intersect(s2 *Scanner) bool {
-	for r := range Rotations {
+// Intersect aligns s1 to s2, if they have enough beacons in common.
+func (s1 *Scanner) Intersect(s2 *Scanner, o Options) (Fit, bool) {
+	for _, r := range Rotations {
 		rb := set.Map(s1.Beacons, r.MulV)
-		Δ := CountDeltas(rb, s2.Beacons)
+		for _, δ := range Candidates(CountDeltas(rb, s2.Beacons), o) {
+			if f, ok := align(rb, s2.Beacons, δ, o); ok {
+				f.Transform.A = r
+				return f, true
+			}
+		}
+	}
+	return Fit{}, false
+}
+
+// Candidates returns the offsets, which are close to enough of the deltas in
+// Δ. Deltas within 2•o.Tolerance of each other are clustered and the mean of
+// a cluster is returned. If o.Tolerance is 0, those are the deltas occurring
+// often enough.
+func Candidates(Δ map[vec.V]int, o Options) []vec.V {
+	k := o.overlap()
+	var out []vec.V
+	if o.Tolerance == 0 {
 		for δ, n := range Δ {
-			if n >= 12 {
-				s1.Transform = Affine{
-					A: r,
-					B: δ,
+			if n >= k {
+				out = append(out, δ)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return less(out[i], out[j]) })
+		return out
+	}
+
+	r := 2 * o.Tolerance
+	size := r
+	// Deltas within r of each other are in the same or in adjacent cells.
+	cell := func(v vec.V) vec.V {
+		div := func(a int) int {
+			if a < 0 {
+				return (a+1)/size - 1
+			}
+			return a / size
+		}
+		return vec.Vec(div(v.X), div(v.Y), div(v.Z))
+	}
+	cells := make(map[vec.V][]vec.V)
+	for δ := range Δ {
+		c := cell(δ)
+		cells[c] = append(cells[c], δ)
+	}
+
+	seen := make(set.Set[vec.V])
+	for δ := range Δ {
+		var (
+			n          int
+			sx, sy, sz float64
+		)
+		c := cell(δ)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for dz := -1; dz <= 1; dz++ {
+					for _, ε := range cells[c.Add(vec.Vec(dx, dy, dz))] {
+						if vec.DistLInf(δ, ε) > r {
+							continue
+						}
+						m := Δ[ε]
+						n += m
+						sx += float64(m * ε.X)
+						sy += float64(m * ε.Y)
+						sz += float64(m * ε.Z)
+					}
 				}
-				return true
 			}
 		}
+		if n < k {
+			continue
+		}
+		mean := func(s float64) int {
+			return int(math.Round(s / float64(n)))
+		}
+		if μ := vec.Vec(mean(sx), mean(sy), mean(sz)); !seen.Contains(μ) {
+			seen.Add(μ)
+			out = append(out, μ)
+		}
 	}
-	return false
+	sort.Slice(out, func(i, j int) bool { return less(out[i], out[j]) })
+	return out
+}
+
+// align matches each beacon in b1, offset by δ, to the closest unmatched
+// beacon in b2, at most 2•o.Tolerance away in each coordinate.
+func align(b1, b2 set.Set[vec.V], δ vec.V, o Options) (Fit, bool) {
+	var (
+		matched = make(set.Set[vec.V])
+		sq      int
+		ws      = sorted(b2)
+	)
+	for _, v := range sorted(b1) {
+		p := v.Add(δ)
+		var (
+			best vec.V
+			dist = -1
+		)
+		for _, w := range ws {
+			d := vec.DistLInf(p, w)
+			if d > 2*o.Tolerance || matched.Contains(w) || (dist >= 0 && d >= dist) {
+				continue
+			}
+			best, dist = w, d
+		}
+		if dist < 0 {
+			continue
+		}
+		matched.Add(best)
+		d := best.Sub(p)
+		sq += d.Mul(d)
+	}
+	n := len(matched)
+	if n < o.overlap() {
+		return Fit{}, false
+	}
+	return Fit{
+		Transform: Affine{B: δ},
+		Matched:   n,
+		Residual:  math.Sqrt(float64(sq) / float64(n)),
+	}, true
 }
 
 func (s1 *Scanner) Pos() vec.V {
@@ -188,3 +373,24 @@ func CountDeltas(b1, b2 set.Set[vec.V]) map[vec.V]int {
 	}
 	return Δ
 }
+
+// sorted returns the vectors in s, ordered by less.
+func sorted(s set.Set[vec.V]) []vec.V {
+	out := make([]vec.V, 0, len(s))
+	for v := range s {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return less(out[i], out[j]) })
+	return out
+}
+
+// less orders vectors lexicographically.
+func less(v, w vec.V) bool {
+	if v.X != w.X {
+		return v.X < w.X
+	}
+	if v.Y != w.Y {
+		return v.Y < w.Y
+	}
+	return v.Z < w.Z
+}
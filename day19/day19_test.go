@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/Merovius/aoc_2021/go/set"
+	"github.com/Merovius/aoc_2021/go/vec"
+)
+
+// table is the hand-written table of rotations, which Rotations replaced.
+var table = []vec.M{
+	vec.Mat(1, 0, 0, 0, 0, -1, 0, 1, 0),
+	vec.Mat(0, 0, 1, 0, 1, 0, -1, 0, 0),
+	vec.Mat(0, -1, 0, 1, 0, 0, 0, 0, 1),
+	vec.Mat(1, 0, 0, 0, -1, 0, 0, 0, -1),
+	vec.Mat(0, 0, 1, 1, 0, 0, 0, 1, 0),
+	vec.Mat(0, -1, 0, 0, 0, -1, 1, 0, 0),
+	vec.Mat(0, 1, 0, 0, 0, -1, -1, 0, 0),
+	vec.Mat(-1, 0, 0, 0, 1, 0, 0, 0, -1),
+	vec.Mat(0, 0, -1, 0, -1, 0, -1, 0, 0),
+	vec.Mat(0, 1, 0, 1, 0, 0, 0, 0, -1),
+	vec.Mat(0, -1, 0, 0, 0, 1, -1, 0, 0),
+	vec.Mat(-1, 0, 0, 0, -1, 0, 0, 0, 1),
+	vec.Mat(-1, 0, 0, 0, 0, 1, 0, 1, 0),
+	vec.Mat(0, 0, 1, 0, -1, 0, 1, 0, 0),
+	vec.Mat(0, -1, 0, -1, 0, 0, 0, 0, -1),
+	vec.Mat(1, 0, 0, 0, 0, 1, 0, -1, 0),
+	vec.Mat(0, 0, -1, 1, 0, 0, 0, -1, 0),
+	vec.Mat(0, 1, 0, 0, 0, 1, 1, 0, 0),
+	vec.Mat(-1, 0, 0, 0, 0, -1, 0, -1, 0),
+	vec.Mat(0, 0, 1, -1, 0, 0, 0, -1, 0),
+	vec.Mat(0, 0, -1, 0, 1, 0, 1, 0, 0),
+	vec.Mat(0, 0, -1, -1, 0, 0, 0, 1, 0),
+	vec.Mat(0, 1, 0, -1, 0, 0, 0, 0, 1),
+	vec.Mat(1, 0, 0, 0, 1, 0, 0, 0, 1),
+}
+
+func TestRotations(t *testing.T) {
+	want := set.Make(table...)
+	got := set.Make(Rotations...)
+	if len(Rotations) != len(want) || len(got) != len(want) {
+		t.Errorf("len(Rotations) = %d, want %d distinct", len(Rotations), len(want))
+	}
+	for r := range want {
+		if !got.Contains(r) {
+			t.Errorf("Rotations does not contain %v", r)
+		}
+	}
+	for _, r := range Rotations {
+		if !want.Contains(r) {
+			t.Errorf("Rotations contains %v, which is not in the table", r)
+		}
+	}
+}
+
+// overlap are the pairs of scanners in example.txt with enough beacons in
+// common.
+var overlap = map[[2]int]bool{{0, 1}: true, {1, 3}: true, {1, 4}: true, {2, 4}: true}
+
+func parseExample(t *testing.T) []*Scanner {
+	t.Helper()
+	f, err := os.Open("example.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanners, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scanners
+}
+
+func TestMayOverlap(t *testing.T) {
+	scanners := parseExample(t)
+	for i, s1 := range scanners {
+		for j, s2 := range scanners[i+1:] {
+			j += i + 1
+			want := overlap[[2]int{i, j}]
+			if got := s1.MayOverlap(s2, Options{}); got != want {
+				t.Errorf("scanner %d MayOverlap(%d) = %v, want %v", i, j, got, want)
+			}
+			f, got := s1.Intersect(s2, Options{})
+			if got != want {
+				t.Errorf("scanner %d Intersect(%d) = _, %v, want %v", i, j, got, want)
+			}
+			if got && f.Residual != 0 {
+				t.Errorf("scanner %d Intersect(%d) has residual %v, want 0", i, j, f.Residual)
+			}
+		}
+	}
+}
+
+// jitter returns scanners, with each coordinate of each beacon moved by at
+// most 1.
+func jitter(scanners []*Scanner) []*Scanner {
+	rnd := rand.New(rand.NewSource(19))
+	d := func() int { return rnd.Intn(3) - 1 }
+	noisy := make([]*Scanner, len(scanners))
+	for i, s := range scanners {
+		bs := make(set.Set[vec.V])
+		for _, b := range sorted(s.Beacons) {
+			bs.Add(b.Add(vec.Vec(d(), d(), d())))
+		}
+		noisy[i] = NewScanner(i, bs)
+	}
+	return noisy
+}
+
+func TestTolerance(t *testing.T) {
+	scanners := parseExample(t)
+	noisy := jitter(scanners)
+
+	for p := range overlap {
+		exact, ok := scanners[p[0]].Intersect(scanners[p[1]], Options{})
+		if !ok {
+			t.Fatalf("scanners %d and %d do not overlap", p[0], p[1])
+		}
+		s1, s2 := noisy[p[0]], noisy[p[1]]
+		if _, ok := s1.Intersect(s2, Options{}); ok {
+			t.Errorf("noisy scanner %d Intersect(%d) without tolerance succeeded", p[0], p[1])
+		}
+		o := Options{Tolerance: 2}
+		if !s1.MayOverlap(s2, o) {
+			t.Errorf("noisy scanner %d MayOverlap(%d) = false, want true", p[0], p[1])
+		}
+		f, ok := s1.Intersect(s2, o)
+		if !ok {
+			t.Errorf("noisy scanner %d Intersect(%d) failed", p[0], p[1])
+			continue
+		}
+		if f.Transform.A != exact.Transform.A || vec.DistLInf(f.Transform.B, exact.Transform.B) > 2 {
+			t.Errorf("noisy scanner %d Intersect(%d) = %v, want about %v", p[0], p[1], f.Transform, exact.Transform)
+		}
+		if f.Matched < MinOverlap || f.Residual == 0 || f.Residual > 2*math.Sqrt(3) {
+			t.Errorf("noisy scanner %d Intersect(%d) matched %d beacons with residual %v", p[0], p[1], f.Matched, f.Residual)
+		}
+	}
+}
+
+func TestOverlapOption(t *testing.T) {
+	scanners := parseExample(t)
+	s1, s2 := scanners[0], scanners[1]
+	if _, ok := s1.Intersect(s2, Options{Overlap: 13}); ok {
+		t.Errorf("Intersect with Overlap 13 succeeded, want failure")
+	}
+	if s1.MayOverlap(scanners[2], Options{Overlap: 3}) != true {
+		t.Errorf("MayOverlap with Overlap 3 = false, want true")
+	}
+}
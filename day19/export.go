@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Merovius/aoc_2021/go/vec"
+)
+
+// Map is the reconstructed map of beacons and scanners, relative to scanner 0.
+type Map struct {
+	Beacons  []Beacon
+	Scanners []*Scanner
+}
+
+// Beacon is a beacon on a Map.
+type Beacon struct {
+	Pos vec.V
+	// Seen are the scanners which have seen the beacon, in increasing order.
+	Seen []int
+}
+
+// Reconstruct returns the map of all beacons seen by the aligned scanners.
+// Beacons at most 2•o.Tolerance apart in each coordinate are merged, using the
+// position measured by the first scanner.
+func Reconstruct(scanners []*Scanner, o Options) *Map {
+	m := &Map{Scanners: scanners}
+	idx := make(map[vec.V]int)
+	for _, s := range scanners {
+	beacons:
+		for _, b := range sorted(s.Beacons) {
+			p := s.Transform.Apply(b)
+			if i, ok := idx[p]; ok {
+				m.addSeen(i, s.N)
+				continue
+			}
+			if o.Tolerance > 0 {
+				for i, b := range m.Beacons {
+					if vec.DistLInf(p, b.Pos) <= 2*o.Tolerance {
+						m.addSeen(i, s.N)
+						continue beacons
+					}
+				}
+			}
+			idx[p] = len(m.Beacons)
+			m.Beacons = append(m.Beacons, Beacon{Pos: p, Seen: []int{s.N}})
+		}
+	}
+	return m
+}
+
+func (m *Map) addSeen(i, n int) {
+	b := &m.Beacons[i]
+	if b.Seen[len(b.Seen)-1] != n {
+		b.Seen = append(b.Seen, n)
+	}
+}
+
+// WritePLY writes m to w as an ASCII PLY point cloud. Beacons are white and
+// followed by the scanners in red. Every vertex has a list of the scanners
+// which saw it; for a scanner, that is itself.
+func (m *Map) WritePLY(w io.Writer) error {
+	buf := new(strings.Builder)
+	buf.WriteString("ply\nformat ascii 1.0\n")
+	fmt.Fprintf(buf, "comment %d beacons, followed by %d scanners\n", len(m.Beacons), len(m.Scanners))
+	fmt.Fprintf(buf, "element vertex %d\n", len(m.Beacons)+len(m.Scanners))
+	for _, p := range []string{"int x", "int y", "int z", "uchar red", "uchar green", "uchar blue", "list uchar int scanners"} {
+		fmt.Fprintf(buf, "property %s\n", p)
+	}
+	buf.WriteString("end_header\n")
+	for _, b := range m.Beacons {
+		fmt.Fprintf(buf, "%d %d %d 255 255 255 %d", b.Pos.X, b.Pos.Y, b.Pos.Z, len(b.Seen))
+		for _, n := range b.Seen {
+			fmt.Fprintf(buf, " %d", n)
+		}
+		buf.WriteByte('\n')
+	}
+	for _, s := range m.Scanners {
+		p := s.Pos()
+		fmt.Fprintf(buf, "%d %d %d 255 0 0 1 %d\n", p.X, p.Y, p.Z, s.N)
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// WriteJSON writes m to w as a JSON object. Positions are arrays of three
+// coordinates.
+func (m *Map) WriteJSON(w io.Writer) error {
+	type beacon struct {
+		Pos  [3]int `json:"pos"`
+		Seen []int  `json:"seen"`
+	}
+	type scanner struct {
+		N    int    `json:"n"`
+		Pos  [3]int `json:"pos"`
+		Path []int  `json:"path"`
+	}
+	var out struct {
+		Beacons  []beacon  `json:"beacons"`
+		Scanners []scanner `json:"scanners"`
+	}
+	for _, b := range m.Beacons {
+		out.Beacons = append(out.Beacons, beacon{[3]int{b.Pos.X, b.Pos.Y, b.Pos.Z}, b.Seen})
+	}
+	for _, s := range m.Scanners {
+		p := s.Pos()
+		out.Scanners = append(out.Scanners, scanner{s.N, [3]int{p.X, p.Y, p.Z}, s.Path})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(out)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func reconstructExample(t *testing.T) *Map {
+	t.Helper()
+	scanners := parseExample(t)
+	if _, err := Align(scanners, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	return Reconstruct(scanners, Options{})
+}
+
+func TestReconstruct(t *testing.T) {
+	m := reconstructExample(t)
+	if len(m.Beacons) != 79 {
+		t.Errorf("Reconstruct(…) has %d beacons, want 79", len(m.Beacons))
+	}
+	seen := make(map[[2]int]int)
+	for _, b := range m.Beacons {
+		for i, n := range b.Seen {
+			for _, k := range b.Seen[i+1:] {
+				seen[[2]int{n, k}]++
+			}
+		}
+	}
+	for p := range overlap {
+		if seen[p] != MinOverlap {
+			t.Errorf("scanners %d and %d saw %d beacons, want %d", p[0], p[1], seen[p], MinOverlap)
+		}
+	}
+}
+
+func TestReconstructTolerance(t *testing.T) {
+	scanners := jitter(parseExample(t))
+	o := Options{Tolerance: 2}
+	if _, err := Align(scanners, o); err != nil {
+		t.Fatal(err)
+	}
+	if m := Reconstruct(scanners, o); len(m.Beacons) != 79 {
+		t.Errorf("Reconstruct(…) has %d beacons, want 79", len(m.Beacons))
+	}
+}
+
+func TestWritePLY(t *testing.T) {
+	m := reconstructExample(t)
+	buf := new(strings.Builder)
+	if err := m.WritePLY(buf); err != nil {
+		t.Fatal(err)
+	}
+	s := bufio.NewScanner(strings.NewReader(buf.String()))
+	var vertices int
+	for s.Scan() && s.Text() != "end_header" {
+		if s.Text() == "element vertex 84" {
+			vertices = -1
+		}
+	}
+	if vertices != -1 {
+		t.Errorf("PLY header does not declare 84 vertices:\n%s", buf)
+	}
+	for vertices = 0; s.Scan(); vertices++ {
+	}
+	if vertices != 84 {
+		t.Errorf("PLY has %d vertices, want 84", vertices)
+	}
+	if !strings.Contains(buf.String(), "\n68 -1246 -43 255 0 0 1 1\n") {
+		t.Errorf("PLY does not contain scanner 1")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	m := reconstructExample(t)
+	buf := new(strings.Builder)
+	if err := m.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Beacons []struct {
+			Pos  [3]int
+			Seen []int
+		}
+		Scanners []struct {
+			N    int
+			Pos  [3]int
+			Path []int
+		}
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Beacons) != 79 || len(got.Scanners) != 5 {
+		t.Fatalf("JSON has %d beacons and %d scanners, want 79 and 5", len(got.Beacons), len(got.Scanners))
+	}
+	if s := got.Scanners[2]; s.Pos != [3]int{1105, -1205, 1229} || len(s.Path) != 4 {
+		t.Errorf("JSON scanner 2 = %+v, want at [1105 -1205 1229] via 4 scanners", s)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Merovius/aoc_2021/go/vec"
+)
+
+// Edge is an alignment of scanner From to scanner To.
+type Edge struct {
+	From, To int
+	Fit      Fit
+}
+
+// OverlapGraph returns the overlap graph of scanners. g[i] are the alignments
+// of the other scanners to scanner i, ordered by scanner.
+func OverlapGraph(scanners []*Scanner, o Options) (g [][]Edge) {
+	g = make([][]Edge, len(scanners))
+	for i, s1 := range scanners {
+		// Scanners before i have already been added to g[i], so it is
+		// ordered.
+		for j, s2 := range scanners[i+1:] {
+			j += i + 1
+			if !s2.MayOverlap(s1, o) {
+				continue
+			}
+			f, ok := s2.Intersect(s1, o)
+			if !ok {
+				continue
+			}
+			g[i] = append(g[i], Edge{From: j, To: i, Fit: f})
+			fi := f
+			fi.Transform = f.Transform.Inverse()
+			g[j] = append(g[j], Edge{From: i, To: j, Fit: fi})
+		}
+	}
+	return g
+}
+
+// DisconnectedError is returned by Align, if some scanners can not be aligned
+// to scanner 0.
+type DisconnectedError struct {
+	// Components are the connected components of the overlap graph, other
+	// than the one of scanner 0.
+	Components [][]int
+}
+
+func (e *DisconnectedError) Error() string {
+	var parts []string
+	for _, c := range e.Components {
+		parts = append(parts, fmt.Sprint(c))
+	}
+	return fmt.Sprintf("scanners not connected to scanner 0: %s", strings.Join(parts, ", "))
+}
+
+// Align aligns all scanners to scanner 0, setting their Transform and Path.
+// It builds a spanning tree of the overlap graph breadth-first, visiting
+// neighbors in order, so every scanner is aligned through as few others as
+// possible and the result does not change between runs. It returns the edges
+// of the tree, in the order they were found.
+//
+// If not all scanners are connected to scanner 0, it returns a
+// *DisconnectedError.
+func Align(scanners []*Scanner, o Options) ([]Edge, error) {
+	g := OverlapGraph(scanners, o)
+	var (
+		tree  []Edge
+		seen  = make([]bool, len(scanners))
+		comps [][]int
+	)
+	for root := range scanners {
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		if root == 0 {
+			scanners[0].Transform = Affine{A: vec.ID()}
+			scanners[0].Path = []int{0}
+		}
+		comp := []int{root}
+		for q := []int{root}; len(q) > 0; q = q[1:] {
+			for _, e := range g[q[0]] {
+				if seen[e.From] {
+					continue
+				}
+				seen[e.From] = true
+				comp = append(comp, e.From)
+				q = append(q, e.From)
+				if root != 0 {
+					continue
+				}
+				from, to := scanners[e.From], scanners[e.To]
+				from.Transform = to.Transform.Compose(e.Fit.Transform)
+				from.Path = append([]int{e.From}, to.Path...)
+				tree = append(tree, e)
+			}
+		}
+		if root != 0 {
+			sort.Ints(comp)
+			comps = append(comps, comp)
+		}
+	}
+	if len(comps) > 0 {
+		return tree, &DisconnectedError{comps}
+	}
+	return tree, nil
+}
+
+// FormatPath formats a Path of a Scanner.
+func FormatPath(p []int) string {
+	var parts []string
+	for _, i := range p {
+		parts = append(parts, strconv.Itoa(i))
+	}
+	return strings.Join(parts, " → ")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Merovius/aoc_2021/go/vec"
+)
+
+func TestAlign(t *testing.T) {
+	scanners := parseExample(t)
+	tree, err := Align(scanners, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][2]int
+	for _, e := range tree {
+		got = append(got, [2]int{e.From, e.To})
+	}
+	if want := [][2]int{{1, 0}, {3, 1}, {4, 1}, {2, 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Align(…) = %v, want %v", got, want)
+	}
+
+	tcs := []struct {
+		pos  vec.V
+		path []int
+	}{
+		{vec.Vec(0, 0, 0), []int{0}},
+		{vec.Vec(68, -1246, -43), []int{1, 0}},
+		{vec.Vec(1105, -1205, 1229), []int{2, 4, 1, 0}},
+		{vec.Vec(-92, -2380, -20), []int{3, 1, 0}},
+		{vec.Vec(-20, -1133, 1061), []int{4, 1, 0}},
+	}
+	for i, tc := range tcs {
+		s := scanners[i]
+		if s.Pos() != tc.pos {
+			t.Errorf("scanner %d is at %v, want %v", i, s.Pos(), tc.pos)
+		}
+		if !reflect.DeepEqual(s.Path, tc.path) {
+			t.Errorf("scanner %d has path %v, want %v", i, s.Path, tc.path)
+		}
+	}
+}
+
+func TestAlignDisconnected(t *testing.T) {
+	ex := parseExample(t)
+	var scanners []*Scanner
+	for i, j := range []int{0, 2, 4, 3} {
+		scanners = append(scanners, NewScanner(i, ex[j].Beacons))
+	}
+	_, err := Align(scanners, Options{})
+	var de *DisconnectedError
+	if !errors.As(err, &de) {
+		t.Fatalf("Align(…) = %v, want *DisconnectedError", err)
+	}
+	if want := [][]int{{1, 2}, {3}}; !reflect.DeepEqual(de.Components, want) {
+		t.Errorf("Align(…) reports components %v, want %v", de.Components, want)
+	}
+}
+
+func TestAffineInverse(t *testing.T) {
+	for _, r := range Rotations {
+		a := Affine{A: r, B: vec.Vec(1, -2, 3)}
+		if got := a.Compose(a.Inverse()); got != (Affine{A: vec.ID()}) {
+			t.Errorf("%v.Compose(%v.Inverse()) = %v, want identity", a, a, got)
+		}
+	}
+}
@@ -1,64 +1,90 @@
-// Solves only part 2
-
-//go:build ignore
-
 package main
 
 import (
+	"flag"
 	"fmt"
-	"sort"
+	"log"
+	"os"
+
+	"github.com/Merovius/aoc_2021/day21/dirac"
 )
 
 func main() {
-	nwin := PlayDirac(State{
-		Pos:    [2]int{4, 7},
-		Player: 1,
-	})
-	fmt.Printf("Nwins after playing Dirac Dice: %v\n", nwin)
-}
+	input := flag.String("input", "input.txt", "file containing the starting positions")
+	board := flag.Int("board", 10, "number of spaces on the board")
+	rolls := flag.Int("rolls", 3, "number of rolls per turn")
+	faces1 := flag.Int("faces1", 100, "number of faces of the deterministic die")
+	target1 := flag.Int("target1", 1000, "score needed to win with the deterministic die")
+	faces2 := flag.Int("faces2", 3, "number of faces of the Dirac die")
+	target2 := flag.Int("target2", 21, "score needed to win with the Dirac die")
+	big := flag.Bool("big", false, "count universes with big integers (needed for targets above 27)")
+	parallel := flag.Bool("parallel", false, "count universes on all CPUs, using big integers")
+	analyze := flag.Bool("analyze", false, "print win probabilities, expected turns and final scores of the Dirac game for all starting positions")
+	format := flag.String("format", "csv", "output format of -analyze (csv or json)")
+	flag.Parse()
 
-type State struct {
-	Pos    [2]int
-	Score  [2]int
-	Player int
-}
-
-var rolls [27]int
-
-func init() {
-	for i := range rolls {
-		rolls[i] = i%3 + (i/3)%3 + (i/9)%3 + 3
+	f, err := os.Open(*input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	start, err := dirac.ParseStart(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", *input, err)
 	}
-	sort.Ints(rolls[:])
-	fmt.Println(rolls)
-}
 
-var (
-	n   = 0
-	mem = make(map[State][2]int)
-)
+	if *analyze {
+		if *format != "csv" && *format != "json" {
+			log.Fatalf("invalid format %q", *format)
+		}
+		// Only the number of players matters.
+		ones := make([]int, len(start))
+		for i := range ones {
+			ones[i] = 1
+		}
+		g, err := dirac.NewGame(*board, *faces2, *rolls, *target2, ones...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		write := dirac.WriteAnalysisCSV
+		if *format == "json" {
+			write = dirac.WriteAnalysisJSON
+		}
+		if err := write(os.Stdout, g.Analyze()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-func PlayDirac(s State) [2]int {
-	if nw, ok := mem[s]; ok {
-		return nw
+	g, err := dirac.NewGame(*board, *faces1, *rolls, *target1, start...)
+	if err != nil {
+		log.Fatal(err)
 	}
-	var nw [2]int
-	for _, r := range rolls[:] {
-		s2 := s
-		s2.Player = (s2.Player + 1) % 2
-		s2.Pos[s2.Player] += r
-		for s2.Pos[s2.Player] > 10 {
-			s2.Pos[s2.Player] -= 10
+	score, n := g.PlayDeterministic()
+	loser := score[0]
+	for _, s := range score {
+		if s < loser {
+			loser = s
 		}
-		s2.Score[s2.Player] += s2.Pos[s2.Player]
-		if s2.Score[s2.Player] >= 21 {
-			nw[s2.Player] += 1
-			continue
+	}
+	fmt.Printf("Scores after playing with deterministic die: %v\n", score)
+	fmt.Printf("Die rolls: %d\n", n)
+	fmt.Printf("Product: %d\n", n*loser)
+
+	g, err = dirac.NewGame(*board, *faces2, *rolls, *target2, start...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var nwin any
+	switch {
+	case *parallel:
+		nwin = g.WinsParallel(0)
+	case *big:
+		nwin = g.WinsLayered()
+	default:
+		if nwin, err = g.Wins(); err != nil {
+			log.Fatalf("%v, try -big", err)
 		}
-		nw2 := PlayDirac(s2)
-		nw[0] += nw2[0]
-		nw[1] += nw2[1]
 	}
-	mem[s] = nw
-	return nw
+	fmt.Printf("Nwins after playing Dirac Dice: %v\n", nwin)
 }
@@ -0,0 +1,149 @@
+package dirac
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Analysis describes the outcome of a game with the die of the game, from
+// given starting positions.
+type Analysis struct {
+	Start []int `json:"start"`
+	// Win is the probability for each player to win.
+	Win []float64 `json:"win"`
+	// Turns is the expected number of turns played.
+	Turns float64 `json:"turns"`
+	// Score[p][k] is the probability for player p to end the game with a
+	// score of k.
+	Score [][]float64 `json:"score"`
+}
+
+// Analyze returns an Analysis for every combination of starting positions,
+// ordered lexicographically. g.Start is ignored, except for the number of
+// players.
+//
+// The results from all states are memoized, so memory is proportional to
+// g.States() • g.Players() • (g.Target+g.Board).
+func (g *Game) Analyze() []Analysis {
+	mem := make([]*Analysis, g.States())
+	var total float64
+	for _, r := range g.dist {
+		total += float64(r.N)
+	}
+	maxScore := g.Target + g.Board
+
+	var play func(s State) *Analysis
+	play = func(s State) *Analysis {
+		i := g.Index(s)
+		if a := mem[i]; a != nil {
+			return a
+		}
+		a := &Analysis{
+			Win:   make([]float64, g.Players()),
+			Score: make([][]float64, g.Players()),
+		}
+		for p := range a.Score {
+			a.Score[p] = make([]float64, maxScore)
+		}
+		for _, r := range g.dist {
+			q := float64(r.N) / total
+			a.Turns += q
+			next, won := g.Move(s, r.Sum)
+			if won {
+				a.Win[s.Player] += q
+				for p, k := range next.Score {
+					a.Score[p][k] += q
+				}
+				continue
+			}
+			o := play(next)
+			a.Turns += q * o.Turns
+			for p := range a.Win {
+				a.Win[p] += q * o.Win[p]
+				for k, x := range o.Score[p] {
+					a.Score[p][k] += q * x
+				}
+			}
+		}
+		mem[i] = a
+		return a
+	}
+
+	var out []Analysis
+	start := make([]int, g.Players())
+	for i := range start {
+		start[i] = 1
+	}
+	for {
+		a := *play(State{Pos: start, Score: make([]int, g.Players())})
+		a.Start = append([]int(nil), start...)
+		out = append(out, a)
+		i := len(start) - 1
+		for ; i >= 0 && start[i] == g.Board; i-- {
+			start[i] = 1
+		}
+		if i < 0 {
+			return out
+		}
+		start[i]++
+	}
+}
+
+// WriteAnalysisJSON writes as to w as a JSON array.
+func WriteAnalysisJSON(w io.Writer, as []Analysis) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(as)
+}
+
+// WriteAnalysisCSV writes as to w as CSV, with one row per Analysis. The
+// columns are the starting positions, the win probabilities, the expected
+// number of turns and the probabilities of the final scores of each player.
+func WriteAnalysisCSV(w io.Writer, as []Analysis) error {
+	if len(as) == 0 {
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	var header []string
+	for p := range as[0].Start {
+		header = append(header, fmt.Sprintf("start%d", p+1))
+	}
+	for p := range as[0].Win {
+		header = append(header, fmt.Sprintf("win%d", p+1))
+	}
+	header = append(header, "turns")
+	for p, s := range as[0].Score {
+		for k := range s {
+			header = append(header, fmt.Sprintf("score%d_%d", p+1, k))
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	f := func(x float64) string {
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	for _, a := range as {
+		var row []string
+		for _, s := range a.Start {
+			row = append(row, strconv.Itoa(s))
+		}
+		for _, x := range a.Win {
+			row = append(row, f(x))
+		}
+		row = append(row, f(a.Turns))
+		for _, s := range a.Score {
+			for _, x := range s {
+				row = append(row, f(x))
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
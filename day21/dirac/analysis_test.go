@@ -0,0 +1,133 @@
+package dirac
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// bruteForceAnalysis adds the outcomes of playing from s to a, weighted by q,
+// by rolling every die separately.
+func bruteForceAnalysis(g *Game, s State, sum, rolls, turns int, q float64, a *Analysis) {
+	if rolls < g.Rolls {
+		for f := 1; f <= g.Faces; f++ {
+			bruteForceAnalysis(g, s, sum+f, rolls+1, turns, q/float64(g.Faces), a)
+		}
+		return
+	}
+	next, won := g.Move(s, sum)
+	if !won {
+		bruteForceAnalysis(g, next, 0, 0, turns+1, q, a)
+		return
+	}
+	a.Win[s.Player] += q
+	a.Turns += q * float64(turns+1)
+	for p, k := range next.Score {
+		a.Score[p][k] += q
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	g, err := NewGame(4, 2, 2, 7, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := g.Analyze()
+	if len(as) != 16 {
+		t.Fatalf("len(Analyze()) = %d, want 16", len(as))
+	}
+	approx := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-9
+	}
+	for i, got := range as {
+		start := []int{i/4 + 1, i%4 + 1}
+		want := Analysis{
+			Start: start,
+			Win:   make([]float64, 2),
+			Score: [][]float64{make([]float64, 11), make([]float64, 11)},
+		}
+		g, err := NewGame(4, 2, 2, 7, start...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bruteForceAnalysis(g, g.Initial(), 0, 0, 0, 1, &want)
+
+		ok := len(got.Start) == 2 && got.Start[0] == start[0] && got.Start[1] == start[1] && approx(got.Turns, want.Turns)
+		for p := range want.Win {
+			ok = ok && approx(got.Win[p], want.Win[p]) && len(got.Score[p]) == len(want.Score[p])
+			for k := range want.Score[p] {
+				ok = ok && approx(got.Score[p][k], want.Score[p][k])
+			}
+		}
+		if !ok {
+			t.Errorf("Analyze()[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestAnalyzePuzzle(t *testing.T) {
+	g, err := NewGame(10, 3, 3, 21, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range g.Analyze() {
+		var sum float64
+		for _, x := range a.Win {
+			sum += x
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("win probabilities from %v add up to %v", a.Start, sum)
+		}
+		for p, s := range a.Score {
+			var sum float64
+			for _, x := range s {
+				sum += x
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Errorf("score probabilities of player %d from %v add up to %v", p+1, a.Start, sum)
+			}
+		}
+		// Every turn scores at least 1 and at most 10 points.
+		if a.Turns < 5 || a.Turns > 41 {
+			t.Errorf("expected number of turns from %v is %v", a.Start, a.Turns)
+		}
+	}
+}
+
+func TestWriteAnalysis(t *testing.T) {
+	g, err := NewGame(3, 2, 1, 3, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := g.Analyze()
+
+	w := new(strings.Builder)
+	if err := WriteAnalysisCSV(w, as); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if len(lines) != 10 {
+		t.Errorf("WriteAnalysisCSV wrote %d lines, want 10", len(lines))
+	}
+	wantHeader := "start1,start2,win1,win2,turns,score1_0,score1_1,score1_2,score1_3,score1_4,score1_5,score2_0,score2_1,score2_2,score2_3,score2_4,score2_5"
+	if lines[0] != wantHeader {
+		t.Errorf("WriteAnalysisCSV header = %q, want %q", lines[0], wantHeader)
+	}
+	// Player 1 always wins in their second turn.
+	if want := "3,3,1,0,3,"; !strings.HasPrefix(lines[9], want) {
+		t.Errorf("WriteAnalysisCSV last line = %q, want prefix %q", lines[9], want)
+	}
+
+	w.Reset()
+	if err := WriteAnalysisJSON(w, as); err != nil {
+		t.Fatal(err)
+	}
+	var got []Analysis
+	if err := json.Unmarshal([]byte(w.String()), &got); err != nil {
+		t.Fatalf("WriteAnalysisJSON wrote invalid JSON: %v", err)
+	}
+	if len(got) != len(as) || got[8].Turns != as[8].Turns {
+		t.Errorf("WriteAnalysisJSON = %+v, want %+v", got, as)
+	}
+}
@@ -0,0 +1,245 @@
+// Package dirac implements games of Dirac Dice.
+package dirac
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// Game is a game of Dirac Dice. Players take turns, rolling a die Rolls times
+// and moving forward by the sum of the rolls on a circular board. Their score
+// increases by the number of the space they land on. The first player to reach
+// Target wins.
+//
+// Every roll splits the universe into one copy per face of the die.
+//
+// The fields of a Game must not be modified after it was created.
+type Game struct {
+	// Board is the number of spaces on the board, numbered 1 to Board.
+	Board int
+	// Faces is the number of faces of the die, numbered 1 to Faces.
+	Faces int
+	// Rolls is the number of times the die is rolled per turn.
+	Rolls int
+	// Target is the score a player needs to win.
+	Target int
+	// Start are the starting positions of the players.
+	Start []int
+
+	dist []Roll
+}
+
+// NewGame returns a new game with the given parameters.
+func NewGame(board, faces, rolls, target int, start ...int) (*Game, error) {
+	if board < 1 || faces < 1 || rolls < 1 || target < 1 {
+		return nil, errors.New("board size, die faces, rolls and target must be positive")
+	}
+	if len(start) == 0 {
+		return nil, errors.New("need at least one player")
+	}
+	for i, p := range start {
+		if p < 1 || p > board {
+			return nil, fmt.Errorf("starting position %d of player %d is not on the board", p, i+1)
+		}
+	}
+	g := &Game{
+		Board:  board,
+		Faces:  faces,
+		Rolls:  rolls,
+		Target: target,
+		Start:  append([]int(nil), start...),
+	}
+	// n[s] is the number of universes in which the rolls so far sum to s.
+	n := []int{1}
+	for i := 0; i < rolls; i++ {
+		m := make([]int, len(n)+faces)
+		for s, k := range n {
+			for f := 1; f <= faces; f++ {
+				var ok bool
+				if m[s+f], ok = mulAdd(m[s+f], k, 1); !ok {
+					return nil, fmt.Errorf("%d rolls of a die with %d faces: %w", rolls, faces, ErrOverflow)
+				}
+			}
+		}
+		n = m
+	}
+	for s, k := range n {
+		if k > 0 {
+			g.dist = append(g.dist, Roll{s, k})
+		}
+	}
+	return g, nil
+}
+
+// Players returns the number of players.
+func (g *Game) Players() int {
+	return len(g.Start)
+}
+
+// Roll is a possible sum of the rolls of a turn.
+type Roll struct {
+	Sum int
+	// N is the number of universes in which the rolls add up to Sum.
+	N int
+}
+
+// Distribution returns the possible sums of the rolls of a turn, in
+// increasing order.
+func (g *Game) Distribution() []Roll {
+	return g.dist
+}
+
+// State is the state of a game in a single universe.
+type State struct {
+	Pos   []int
+	Score []int
+	// Player is the player to move next.
+	Player int
+}
+
+// Initial returns the state at the start of the game.
+func (g *Game) Initial() State {
+	return State{
+		Pos:   append([]int(nil), g.Start...),
+		Score: make([]int, g.Players()),
+	}
+}
+
+// Move returns the state after the player to move next moves sum spaces. It
+// returns whether that player has won.
+func (g *Game) Move(s State, sum int) (next State, won bool) {
+	p := s.Player
+	next = State{
+		Pos:    append([]int(nil), s.Pos...),
+		Score:  append([]int(nil), s.Score...),
+		Player: (p + 1) % g.Players(),
+	}
+	next.Pos[p] = (next.Pos[p]+sum-1)%g.Board + 1
+	next.Score[p] += next.Pos[p]
+	return next, next.Score[p] >= g.Target
+}
+
+// States returns the number of states in which no player has won yet.
+func (g *Game) States() int {
+	n := g.Players()
+	for range g.Start {
+		n *= g.Board * g.Target
+	}
+	return n
+}
+
+// Index returns a unique index of s in [0, g.States()). No player may have won
+// in s.
+func (g *Game) Index(s State) int {
+	i := s.Player
+	for p := range s.Pos {
+		i = i*g.Board + s.Pos[p] - 1
+		i = i*g.Target + s.Score[p]
+	}
+	return i
+}
+
+// ErrOverflow is returned by NewGame and Wins, if the number of universes does
+// not fit into an int.
+var ErrOverflow = errors.New("number of universes overflows")
+
+// mulAdd returns a+b*c for non-negative a, b and c. It returns false, if the
+// result overflows.
+func mulAdd(a, b, c int) (int, bool) {
+	hi, lo := bits.Mul64(uint64(b), uint64(c))
+	sum, carry := bits.Add64(uint64(a), lo, 0)
+	return int(sum), hi == 0 && carry == 0 && sum <= math.MaxInt
+}
+
+// Wins returns the number of universes in which each player wins.
+//
+// For large targets, the counts overflow and Wins returns ErrOverflow. Use
+// WinsLayered or WinsParallel instead.
+func (g *Game) Wins() ([]int, error) {
+	mem := make(map[int][]int)
+	overflow := false
+	// muladd returns a+b*c, noting overflows.
+	muladd := func(a, b, c int) int {
+		n, ok := mulAdd(a, b, c)
+		overflow = overflow || !ok
+		return n
+	}
+	var play func(s State) []int
+	play = func(s State) []int {
+		i := g.Index(s)
+		if nw, ok := mem[i]; ok {
+			return nw
+		}
+		nw := make([]int, g.Players())
+		for _, r := range g.dist {
+			next, won := g.Move(s, r.Sum)
+			if won {
+				nw[s.Player] = muladd(nw[s.Player], r.N, 1)
+				continue
+			}
+			for p, n := range play(next) {
+				nw[p] = muladd(nw[p], r.N, n)
+			}
+		}
+		mem[i] = nw
+		return nw
+	}
+	nw := play(g.Initial())
+	if overflow {
+		return nil, ErrOverflow
+	}
+	return nw, nil
+}
+
+// PlayDeterministic plays the game with a deterministic die, which rolls 1,
+// 2, …, g.Faces and then starts over. It returns the final scores and the
+// number of times the die was rolled.
+func (g *Game) PlayDeterministic() (score []int, rolls int) {
+	s := g.Initial()
+	for {
+		sum := 0
+		for i := 0; i < g.Rolls; i++ {
+			sum += rolls%g.Faces + 1
+			rolls++
+		}
+		var won bool
+		if s, won = g.Move(s, sum); won {
+			return s.Score, rolls
+		}
+	}
+}
+
+// ParseStart parses the starting positions of the players from r, in the
+// format of the puzzle input:
+//
+//	Player 1 starting position: 4
+//	Player 2 starting position: 8
+func ParseStart(r io.Reader) ([]int, error) {
+	var start []int
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var player, pos int
+		if _, err := fmt.Sscanf(s.Text(), "Player %d starting position: %d", &player, &pos); err != nil {
+			return nil, fmt.Errorf("line %q: %w", s.Text(), err)
+		}
+		if player != len(start)+1 {
+			return nil, fmt.Errorf("got player %d, want %d", player, len(start)+1)
+		}
+		start = append(start, pos)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(start) == 0 {
+		return nil, errors.New("no players")
+	}
+	return start, nil
+}
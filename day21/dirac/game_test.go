@@ -0,0 +1,251 @@
+package dirac
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDistribution(t *testing.T) {
+	tcs := []struct {
+		faces, rolls int
+		want         []Roll
+	}{
+		{3, 3, []Roll{{3, 1}, {4, 3}, {5, 6}, {6, 7}, {7, 6}, {8, 3}, {9, 1}}},
+		{6, 1, []Roll{{1, 1}, {2, 1}, {3, 1}, {4, 1}, {5, 1}, {6, 1}}},
+		{2, 2, []Roll{{2, 1}, {3, 2}, {4, 1}}},
+	}
+	for _, tc := range tcs {
+		g, err := NewGame(10, tc.faces, tc.rolls, 21, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g.Distribution(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Distribution(faces=%d, rolls=%d) = %v, want %v", tc.faces, tc.rolls, got, tc.want)
+		}
+	}
+}
+
+func TestWins(t *testing.T) {
+	tcs := []struct {
+		start []int
+		want  []int
+	}{
+		{[]int{4, 8}, []int{444356092776315, 341960390180808}},
+		{[]int{4, 7}, []int{568867175661958, 408746284676519}},
+	}
+	for _, tc := range tcs {
+		g, err := NewGame(10, 3, 3, 21, tc.start...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := g.Wins(); err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Wins(%v) = %v, %v, want %v, <nil>", tc.start, got, err, tc.want)
+		}
+	}
+	g, err := NewGame(10, 3, 3, 28, 4, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := g.Wins(); err != ErrOverflow {
+		t.Errorf("Wins(target=28) = %v, %v, want %v", got, err, ErrOverflow)
+	}
+}
+
+// bruteForce returns the number of universes in which each player wins, by
+// rolling every die separately.
+func bruteForce(g *Game, s State, sum, rolls int, nw []int) {
+	if rolls < g.Rolls {
+		for f := 1; f <= g.Faces; f++ {
+			bruteForce(g, s, sum+f, rolls+1, nw)
+		}
+		return
+	}
+	next, won := g.Move(s, sum)
+	if won {
+		nw[s.Player]++
+		return
+	}
+	bruteForce(g, next, 0, 0, nw)
+}
+
+func TestWinsGeneric(t *testing.T) {
+	tcs := []struct {
+		board, faces, rolls, target int
+		start                       []int
+	}{
+		{4, 2, 1, 6, []int{1, 2, 3}},
+		{5, 3, 2, 8, []int{5}},
+		{5, 2, 2, 5, []int{1, 5, 3, 4}},
+	}
+	for _, tc := range tcs {
+		g, err := NewGame(tc.board, tc.faces, tc.rolls, tc.target, tc.start...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make([]int, len(tc.start))
+		bruteForce(g, g.Initial(), 0, 0, want)
+		if got, err := g.Wins(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%+v.Wins() = %v, %v, want %v, <nil>", *g, got, err, want)
+		}
+	}
+}
+
+func TestPlayDeterministic(t *testing.T) {
+	tcs := []struct {
+		start     []int
+		wantScore []int
+		wantRolls int
+	}{
+		{[]int{4, 8}, []int{1000, 745}, 993},
+		{[]int{4, 7}, []int{1000, 900}, 993},
+	}
+	for _, tc := range tcs {
+		g, err := NewGame(10, 100, 3, 1000, tc.start...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		score, rolls := g.PlayDeterministic()
+		if !reflect.DeepEqual(score, tc.wantScore) || rolls != tc.wantRolls {
+			t.Errorf("PlayDeterministic(%v) = %v, %d, want %v, %d", tc.start, score, rolls, tc.wantScore, tc.wantRolls)
+		}
+	}
+}
+
+func TestParseStart(t *testing.T) {
+	tcs := []struct {
+		input string
+		want  []int
+		err   bool
+	}{
+		{"Player 1 starting position: 4\nPlayer 2 starting position: 8\n", []int{4, 8}, false},
+		{"Player 1 starting position: 10\n\nPlayer 2 starting position: 1\nPlayer 3 starting position: 3", []int{10, 1, 3}, false},
+		{"", nil, true},
+		{"Player 2 starting position: 4\n", nil, true},
+		{"Player 1 starting position: x\n", nil, true},
+		{"Player 1 starts at 4\n", nil, true},
+	}
+	for _, tc := range tcs {
+		got, err := ParseStart(strings.NewReader(tc.input))
+		if (err != nil) != tc.err || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseStart(%q) = %v, %v, want %v, error=%v", tc.input, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestNewGame(t *testing.T) {
+	tcs := []struct {
+		board, faces, rolls, target int
+		start                       []int
+	}{
+		{0, 3, 3, 21, []int{1}},
+		{10, 0, 3, 21, []int{1}},
+		{10, 3, 0, 21, []int{1}},
+		{10, 3, 3, 0, []int{1}},
+		{10, 3, 3, 21, nil},
+		{10, 3, 3, 21, []int{0}},
+		{10, 3, 3, 21, []int{4, 11}},
+	}
+	for _, tc := range tcs {
+		if _, err := NewGame(tc.board, tc.faces, tc.rolls, tc.target, tc.start...); err == nil {
+			t.Errorf("NewGame(%d, %d, %d, %d, %v) = _, <nil>, want error", tc.board, tc.faces, tc.rolls, tc.target, tc.start)
+		}
+	}
+	// With 10 rolls of a 100-sided die, the most likely sum occurs in about
+	// 4.3e17 universes. With 11 rolls, it overflows.
+	if _, err := NewGame(10, 100, 10, 21, 1); err != nil {
+		t.Errorf("NewGame(faces=100, rolls=10) = _, %v, want <nil>", err)
+	}
+	if _, err := NewGame(10, 100, 11, 21, 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("NewGame(faces=100, rolls=11) = _, %v, want %v", err, ErrOverflow)
+	}
+}
+
+func TestWinsLayered(t *testing.T) {
+	tcs := []struct {
+		board, faces, rolls, target int
+		start                       []int
+	}{
+		{10, 3, 3, 21, []int{4, 8}},
+		{10, 3, 3, 21, []int{4, 7}},
+		{4, 2, 1, 6, []int{1, 2, 3}},
+		{5, 3, 2, 8, []int{5}},
+		{5, 2, 2, 5, []int{1, 5, 3, 4}},
+		{6, 3, 2, 15, []int{2, 6, 3}},
+	}
+	for _, tc := range tcs {
+		g, err := NewGame(tc.board, tc.faces, tc.rolls, tc.target, tc.start...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nw, err := g.Wins()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprint(nw)
+		if got := fmt.Sprint(g.WinsLayered()); got != want {
+			t.Errorf("%+v.WinsLayered() = %v, want %v", *g, got, want)
+		}
+		for _, workers := range []int{1, 3, 8} {
+			if got := fmt.Sprint(g.WinsParallel(workers)); got != want {
+				t.Errorf("%+v.WinsParallel(%d) = %v, want %v", *g, workers, got, want)
+			}
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+	// Computed with the original day21_parallel.go.
+	g, err := NewGame(10, 3, 3, 100, 4, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[70034324651428661338287696860010614177895270171678455239862879359879 64876549977175637694625647758016058287210318465688517394379840364236]"
+	if got := fmt.Sprint(g.WinsLayered()); got != want {
+		t.Errorf("WinsLayered(target=100) = %v, want %v", got, want)
+	}
+	if got := fmt.Sprint(g.WinsParallel(0)); got != want {
+		t.Errorf("WinsParallel(target=100) = %v, want %v", got, want)
+	}
+}
+
+func BenchmarkWins(b *testing.B) {
+	g, err := NewGame(10, 3, 3, 100, 4, 7)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Layered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g.WinsLayered()
+		}
+	})
+	for _, workers := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("Parallel/%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g.WinsParallel(workers)
+			}
+		})
+	}
+}
+
+// TestWinsParallelStress runs WinsParallel with many workers on a small game,
+// so they share every layer. Run it with -race.
+func TestWinsParallelStress(t *testing.T) {
+	g, err := NewGame(10, 3, 3, 15, 4, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nw, err := g.Wins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprint(nw)
+	for i := 0; i < 20; i++ {
+		if got := fmt.Sprint(g.WinsParallel(32)); got != want {
+			t.Fatalf("WinsParallel(32) = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package dirac
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+)
+
+// WinsLayered returns the number of universes in which each player wins, like
+// Wins, but without overflowing.
+//
+// It computes the number of wins from every state bottom-up, in order of
+// decreasing total score of all players. A move increases the total score by
+// at most g.Board, so only g.Board+1 layers of results need to be kept.
+// Results are only stored for states in which the first player moves next, as
+// the others follow by renumbering the players. With N players, the memory
+// used is thus proportional to
+//
+//	(g.Board+1) • N • g.Board^N • g.Target^(N-1)
+//
+// times the size of the results, which grows linearly with g.Target.
+func (g *Game) WinsLayered() []*big.Int {
+	return g.winsLayered(1)
+}
+
+// layerChunk is the number of entries of a layer a worker computes at once.
+const layerChunk = 256
+
+// winsLayered implements WinsLayered and WinsParallel. As a layer only depends
+// on later layers, its entries are split among the workers.
+func (g *Game) winsLayered(workers int) []*big.Int {
+	n := g.Players()
+	size := g.layerSize()
+	layers := make([][]big.Int, g.Board+1)
+	for total := n * (g.Target - 1); total >= 0; total-- {
+		if layers[total%len(layers)] == nil {
+			layers[total%len(layers)] = make([]big.Int, size*n)
+		}
+		if workers == 1 {
+			g.computeLayer(layers, total, 0, size)
+			continue
+		}
+		var (
+			todo int64
+			wg   sync.WaitGroup
+		)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					lo := int(atomic.AddInt64(&todo, layerChunk) - layerChunk)
+					if lo >= size {
+						return
+					}
+					hi := lo + layerChunk
+					if hi > size {
+						hi = size
+					}
+					g.computeLayer(layers, total, lo, hi)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	s := g.Initial()
+	i := g.layerIndex(s.Pos, s.Score)
+	nw := make([]*big.Int, n)
+	for p := range nw {
+		nw[p] = new(big.Int).Set(&layers[0][i*n+p])
+	}
+	return nw
+}
+
+// computeLayer computes the entries [lo, hi) of the layer with the given total
+// score. The later layers must already be computed.
+func (g *Game) computeLayer(layers [][]big.Int, total, lo, hi int) {
+	var (
+		n      = g.Players()
+		l      = layers[total%len(layers)]
+		pos    = make([]int, n)
+		score  = make([]int, n)
+		npos   = make([]int, n)
+		nscore = make([]int, n)
+		rn     big.Int
+		tmp    big.Int
+	)
+	for i := lo * n; i < hi*n; i++ {
+		l[i].SetInt64(0)
+	}
+	for e := lo; e < hi; e++ {
+		if !g.decodeLayer(e, total, pos, score) {
+			continue
+		}
+		out := l[e*n : (e+1)*n]
+		// After the first player moved, the players are renumbered, so
+		// that the next one becomes the first.
+		copy(npos, pos[1:])
+		copy(nscore, score[1:])
+		for _, r := range g.dist {
+			rn.SetInt64(int64(r.N))
+			p := (pos[0]+r.Sum-1)%g.Board + 1
+			s := score[0] + p
+			if s >= g.Target {
+				out[0].Add(&out[0], &rn)
+				continue
+			}
+			npos[n-1], nscore[n-1] = p, s
+			j := g.layerIndex(npos, nscore)
+			next := layers[(total+p)%len(layers)][j*n : (j+1)*n]
+			for q := range out {
+				out[q].Add(&out[q], tmp.Mul(&next[(q+n-1)%n], &rn))
+			}
+		}
+	}
+}
+
+// layerSize returns the number of indices of states with the same total score,
+// in which the first player moves next.
+func (g *Game) layerSize() int {
+	n := 1
+	for range g.Start {
+		n *= g.Board
+	}
+	for range g.Start[1:] {
+		n *= g.Target
+	}
+	return n
+}
+
+// layerIndex returns the index of a state among the states with the same total
+// score, in which the first player moves next. The score of the last player is
+// implied by the total.
+func (g *Game) layerIndex(pos, score []int) int {
+	var i int
+	for _, p := range pos {
+		i = i*g.Board + p - 1
+	}
+	for _, s := range score[:len(score)-1] {
+		i = i*g.Target + s
+	}
+	return i
+}
+
+// decodeLayer stores the positions and scores of the state with index i and
+// the given total score in pos and score. It returns false, if there is no
+// such state.
+func (g *Game) decodeLayer(i, total int, pos, score []int) bool {
+	last := total
+	for p := len(score) - 2; p >= 0; p-- {
+		score[p] = i % g.Target
+		i /= g.Target
+		last -= score[p]
+	}
+	if last < 0 || last >= g.Target {
+		return false
+	}
+	score[len(score)-1] = last
+	for p := len(pos) - 1; p >= 0; p-- {
+		pos[p] = i%g.Board + 1
+		i /= g.Board
+	}
+	return true
+}
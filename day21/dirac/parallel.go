@@ -0,0 +1,19 @@
+package dirac
+
+import (
+	"math/big"
+	"runtime"
+)
+
+// WinsParallel returns the number of universes in which each player wins, like
+// WinsLayered, but computes every layer on the given number of goroutines. If
+// workers is 0, one goroutine per CPU is used.
+//
+// The memory used is the same as for WinsLayered. The result does not depend
+// on the number of workers.
+func (g *Game) WinsParallel(workers int) []*big.Int {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return g.winsLayered(workers)
+}
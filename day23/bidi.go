@@ -0,0 +1,275 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/Merovius/aoc_2021/go/priority_queue"
+)
+
+// Predecessors returns the states which have s as one of their Neighbors,
+// together with the move leading to s. That is, it inverts Neighbors, so a
+// backward search explores the same graph as a forward one.
+//
+// Only the amphipod moved last can be moved back. If it is in the hallway, it
+// came from the cell directly above the amphipods in a room, where it must not
+// have been settled. If it is in a room, it went home, so it is settled and
+// on top. It came from the hallway or from the top of another room. The
+// predecessor is discarded, if Neighbors would have moved a different amphipod
+// home instead.
+func (b *Burrow) Predecessors(s State) []Neighbor {
+	return b.predecessors(s, nil)
+}
+
+// predecessors returns the Predecessors of s, for which keep returns true. keep
+// may be nil.
+func (b *Burrow) predecessors(s State, keep func(State) bool) []Neighbor {
+	var out []Neighbor
+	// add adds the predecessor in which the amphipod at dst is still at src.
+	add := func(src, dst int) {
+		if src < 0 || !b.clear(s, dst, src) {
+			return
+		}
+		p := s
+		p[src], p[dst] = s[dst], None
+		if b.settled(p, src) || (keep != nil && !keep(p)) {
+			return
+		}
+		if h := b.firstHome(p); (b.IsHallway(dst) && h >= 0) || (!b.IsHallway(dst) && h != src) {
+			return
+		}
+		out = append(out, Neighbor{Step{src, dst, s[dst].Cost() * b.Path(src, dst).Length}, p})
+	}
+	for dst := 0; dst < b.Cells(); dst++ {
+		a := s[dst]
+		if a == None {
+			continue
+		}
+		if b.IsHallway(dst) {
+			for r := 0; r < b.Rooms; r++ {
+				add(b.vacancy(s, r), dst)
+			}
+			continue
+		}
+		if !b.settled(s, dst) || (dst >= b.Cell(0, 1) && s[dst-b.Rooms] != None) {
+			continue
+		}
+		for src := range b.columns {
+			if s[src] == None {
+				add(src, dst)
+			}
+		}
+		for r := 0; r < b.Rooms; r++ {
+			if r != int(a-A) {
+				add(b.vacancy(s, r), dst)
+			}
+		}
+	}
+	return out
+}
+
+// vacancy returns the free cell directly above the amphipods in room r, or -1
+// if the room is full.
+func (b *Burrow) vacancy(s State, r int) int {
+	v := -1
+	for row := 0; row < b.Depth && s[b.Cell(r, row)] == None; row++ {
+		v = b.Cell(r, row)
+	}
+	return v
+}
+
+// firstHome returns the first cell from which an amphipod can move home in s,
+// or -1. This is the move Neighbors returns, if there is one.
+func (b *Burrow) firstHome(s State) int {
+	for src := 0; src < b.Cells(); src++ {
+		a := s[src]
+		if a == None || b.settled(s, src) {
+			continue
+		}
+		if t := b.target(s, a); t >= 0 && b.clear(s, src, t) {
+			return src
+		}
+	}
+	return -1
+}
+
+// mayReach returns false, if t can't be reached from s.
+//
+// Amphipods never enter a room other than their home. So every amphipod in a
+// room is either in its cell in s or at home.
+func (b *Burrow) mayReach(s, t State) bool {
+	for i := len(b.columns); i < b.Cells(); i++ {
+		if t[i] != None && t[i] != s[i] && !b.IsHome(t[i], i) {
+			return false
+		}
+	}
+	return true
+}
+
+// organizeBidirectional runs Dijkstra's algorithm or A* forward from s and
+// backward from the end, until the searches meet.
+//
+// For the backward search, the parent of a state is its successor. It skips
+// states which can't be reached from s.
+//
+// With opts.AStar, both searches use the average of the forward heuristic and
+// a backward one as potential, so they search the same graph with reduced,
+// non-negative costs and the stopping rule of Dijkstra's algorithm stays
+// valid. The backward heuristic is Heuristic(s) - Heuristic(v). It may be
+// negative, but as Heuristic is consistent, so is it, which is all the
+// potential needs. Potentials are doubled, to stay integers.
+//
+// Both searches settle all states with the smallest priority of the direction
+// with the smaller frontier at once. Their neighbors are generated by
+// opts.Workers goroutines. The result does not depend on the number of
+// workers.
+func (b *Burrow) organizeBidirectional(s State, opts Options) (Result, error) {
+	type QEntry struct {
+		prio int
+		cost int
+		from Key
+		step Step
+		to   Key
+	}
+	type search struct {
+		q       *priority_queue.Q[QEntry]
+		visited map[Key]parent
+		expand  func(State) []Neighbor
+		// sign is the sign of the potential in the priority.
+		sign int
+	}
+	hs := b.Heuristic(s)
+	pot := func(s State) int {
+		if !opts.AStar {
+			return 0
+		}
+		hf := b.Heuristic(s)
+		hb := hs - hf
+		return hf - hb
+	}
+	newSearch := func(s State, expand func(State) []Neighbor, sign int) *search {
+		q := priority_queue.NewFunc(func(a, b QEntry) bool {
+			return a.prio < b.prio
+		})
+		k := s.Key()
+		q.Push(QEntry{sign * pot(s), 0, k, Step{}, k})
+		return &search{q, make(map[Key]parent), expand, sign}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	start, end := s.Key(), b.End().Key()
+	backward := func(v State) []Neighbor {
+		return b.predecessors(v, func(p State) bool { return b.mayReach(s, p) })
+	}
+	fwd, bwd := newSearch(s, b.Neighbors, 1), newSearch(b.End(), backward, -1)
+
+	// The cheapest path found so far consists of the forward path to
+	// meet.from, meet.step (unless meet.from == meet.to) and the backward
+	// path from meet.to.
+	var meet QEntry
+	best := -1
+	found := func(cost int, from Key, step Step, to Key) {
+		if best < 0 || cost < best {
+			best, meet = cost, QEntry{cost, cost, from, step, to}
+		}
+	}
+	// top discards visited states from the frontier of x and returns the
+	// smallest priority in it. ok is false if it is empty.
+	top := func(x *search) (prio int, ok bool) {
+		for x.q.Len() > 0 {
+			e := x.q.Pop()
+			if _, ok := x.visited[e.to]; !ok {
+				x.q.Push(e)
+				return e.prio, true
+			}
+		}
+		return 0, false
+	}
+
+	for {
+		tf, okf := top(fwd)
+		tb, okb := top(bwd)
+		if !okf || !okb || (best >= 0 && tf+tb >= 2*best) {
+			break
+		}
+		x, y, forward, prio := fwd, bwd, true, tf
+		if bwd.q.Len() < fwd.q.Len() {
+			x, y, forward, prio = bwd, fwd, false, tb
+		}
+
+		var batch []QEntry
+		for x.q.Len() > 0 {
+			e := x.q.Pop()
+			if e.prio > prio {
+				x.q.Push(e)
+				break
+			}
+			if _, ok := x.visited[e.to]; ok {
+				continue
+			}
+			x.visited[e.to] = parent{e.cost, e.from, e.step}
+			batch = append(batch, e)
+			if p, ok := y.visited[e.to]; ok {
+				found(e.cost+p.cost, e.to, Step{}, e.to)
+			}
+		}
+
+		ns := make([][]Neighbor, len(batch))
+		var wg sync.WaitGroup
+		for w := 0; w < workers && w < len(batch); w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < len(batch); i += workers {
+					ns[i] = x.expand(batch[i].to.State())
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		for i, e := range batch {
+			for _, n := range ns[i] {
+				k := n.State.Key()
+				if _, ok := x.visited[k]; ok {
+					continue
+				}
+				c := e.cost + n.Step.Cost
+				x.q.Push(QEntry{2*c + x.sign*pot(n.State), c, e.to, n.Step, k})
+				if p, ok := y.visited[k]; ok {
+					if forward {
+						found(c+p.cost, e.to, n.Step, k)
+					} else {
+						found(c+p.cost, k, n.Step, e.to)
+					}
+				}
+			}
+		}
+	}
+	r := Result{Expanded: len(fwd.visited) + len(bwd.visited)}
+	if opts.Explore {
+		r.Explored = append(explored(fwd.visited, false), explored(bwd.visited, true)...)
+	}
+	if best < 0 {
+		return r, ErrNoSolution
+	}
+	r.Energy = best
+	for t := meet.from; t != start; {
+		p := fwd.visited[t]
+		r.Steps = append(r.Steps, p.step)
+		t = p.from
+	}
+	for i, j := 0, len(r.Steps)-1; i < j; i, j = i+1, j-1 {
+		r.Steps[i], r.Steps[j] = r.Steps[j], r.Steps[i]
+	}
+	if meet.from != meet.to {
+		r.Steps = append(r.Steps, meet.step)
+	}
+	for t := meet.to; t != end; {
+		p := bwd.visited[t]
+		r.Steps = append(r.Steps, p.step)
+		t = p.from
+	}
+	return r, nil
+}
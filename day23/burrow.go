@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Merovius/aoc_2021/go/priority_queue"
+)
+
+// Burrow is the topology of a burrow. It consists of a hallway and a number
+// of rooms of equal depth, which open into the hallway via doors.
+//
+// Occupiable positions are numbered top-to-bottom, left-to-right, skipping
+// the cells in front of doors. For the burrow of part 2, this is
+//
+//	•••••••••••••••••••
+//	•0 1. 2. 3. 4. 5 6•
+//	••••7 •8 •9 •10••••
+//	   •11•12•13•14•
+//	   •15•16•17•18•
+//	   •19•20•21•22•
+//	   •••••••••••••
+type Burrow struct {
+	// Rooms is the number of rooms, which is also the number of types of
+	// amphipods.
+	Rooms int
+	// Depth is the number of cells in each room.
+	Depth int
+	// Hallway is the length of the hallway.
+	Hallway int
+	// Doors are the hallway columns of the doors of each room.
+	Doors []int
+
+	// columns are the columns of the occupiable hallway cells.
+	columns []int
+	// edges is the cost associated with a given edge, including edges to
+	// the cells in front of doors. If an edge is not in edges, it doesn't
+	// exist.
+	edges map[Edge]int
+	// paths are the shortest paths between any two positions.
+	paths [][]Path
+	// enter[i][r] is the shortest distance from position i to the top of
+	// room r. If i is in room r, the amphipod has to leave the room first.
+	enter [][]int
+}
+
+// MaxCells is the maximum number of cells in a burrow.
+const MaxCells = 42
+
+// NewBurrow returns a burrow with the given number of rooms of the given
+// depth. doors gives the column of the door of each room in the hallway.
+func NewBurrow(rooms, depth, hallway int, doors []int) (*Burrow, error) {
+	if rooms < 1 || rooms > int(maxCell) {
+		return nil, fmt.Errorf("number of rooms must be between 1 and %d", maxCell)
+	}
+	if depth < 1 {
+		return nil, errors.New("depth of rooms must be positive")
+	}
+	if len(doors) != rooms {
+		return nil, fmt.Errorf("got %d doors for %d rooms", len(doors), rooms)
+	}
+	for i, d := range doors {
+		if d < 0 || d >= hallway {
+			return nil, fmt.Errorf("door %d is outside the hallway", i)
+		}
+		if i > 0 && d <= doors[i-1] {
+			return nil, errors.New("doors must be in increasing order")
+		}
+	}
+	b := &Burrow{
+		Rooms:   rooms,
+		Depth:   depth,
+		Hallway: hallway,
+		Doors:   append([]int(nil), doors...),
+		edges:   make(map[Edge]int),
+	}
+	for col, d := 0, 0; col < hallway; col++ {
+		if d < len(doors) && doors[d] == col {
+			d++
+			continue
+		}
+		b.columns = append(b.columns, col)
+	}
+	if b.Cells() > MaxCells {
+		return nil, fmt.Errorf("burrow has %d cells, at most %d are supported", b.Cells(), MaxCells)
+	}
+
+	// The cells in front of doors are not occupiable, but amphipods pass
+	// them, so they are part of the graph. Door r is node b.Cells()+r.
+	edge := func(i, j, c int) {
+		b.edges[Edge{i, j}] = c
+		b.edges[Edge{j, i}] = c
+	}
+	prev, prevCol := -1, 0
+	for col, d, i := 0, 0, 0; col < hallway; col++ {
+		var n int
+		if d < len(doors) && doors[d] == col {
+			n = b.Cells() + d
+			edge(n, b.Cell(d, 0), 1)
+			for k := 1; k < depth; k++ {
+				edge(b.Cell(d, k-1), b.Cell(d, k), 1)
+			}
+			d++
+		} else {
+			n = i
+			i++
+		}
+		if prev >= 0 {
+			edge(prev, n, col-prevCol)
+		}
+		prev, prevCol = n, col
+	}
+
+	b.paths = make([][]Path, b.Cells())
+	for i := range b.paths {
+		b.paths[i] = b.findShortestPaths(i)
+	}
+	b.enter = make([][]int, b.Cells())
+	for i := range b.enter {
+		b.enter[i] = make([]int, rooms)
+		for r := range doors {
+			top := b.Cell(r, 0)
+			if b.IsHallway(i) || !b.IsHome(A+Cell(r), i) {
+				b.enter[i][r] = b.Path(i, top).Length
+				continue
+			}
+			d := -1
+			for h := range b.columns {
+				if l := b.Path(i, h).Length + b.Path(h, top).Length; d < 0 || l < d {
+					d = l
+				}
+			}
+			if d < 0 {
+				// Without a hallway, the amphipod can't leave.
+				d = b.Path(i, top).Length
+			}
+			b.enter[i][r] = d
+		}
+	}
+	return b, nil
+}
+
+// StandardBurrow returns the burrow of the puzzle, with rooms of the given
+// depth.
+func StandardBurrow(depth int) *Burrow {
+	b, err := NewBurrow(4, depth, 11, []int{2, 4, 6, 8})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Cells returns the number of occupiable cells in b.
+func (b *Burrow) Cells() int {
+	return len(b.columns) + b.Rooms*b.Depth
+}
+
+// Cell returns the position of the given row of the given room. Row 0 is the
+// top of the room.
+func (b *Burrow) Cell(room, row int) int {
+	return len(b.columns) + row*b.Rooms + room
+}
+
+// IsHallway returns whether position i is in the hallway.
+func (b *Burrow) IsHallway(i int) bool {
+	return i < len(b.columns)
+}
+
+// Homes returns the home-cells of amphipods of type c, from top to bottom.
+func (b *Burrow) Homes(c Cell) []int {
+	out := make([]int, b.Depth)
+	for i := range out {
+		out[i] = b.Cell(int(c-A), i)
+	}
+	return out
+}
+
+// IsHome returns whether position i is a home-cell of amphipods of type c.
+func (b *Burrow) IsHome(c Cell, i int) bool {
+	return !b.IsHallway(i) && i < b.Cells() && (i-len(b.columns))%b.Rooms == int(c-A)
+}
+
+// End returns the state in which all amphipods are organized.
+func (b *Burrow) End() State {
+	var s State
+	for r := 0; r < b.Rooms; r++ {
+		for _, h := range b.Homes(A + Cell(r)) {
+			s[h] = A + Cell(r)
+		}
+	}
+	return s
+}
+
+// Path returns the shortest path from src to dst.
+func (b *Burrow) Path(src, dst int) Path {
+	return b.paths[src][dst]
+}
+
+// Edge is an edge between two cells
+type Edge [2]int
+
+type Path struct {
+	Length int
+	Nodes  []int
+}
+
+func (b *Burrow) appendPath(p Path, node int) Path {
+	last := p.Nodes[len(p.Nodes)-1]
+	c, ok := b.edges[Edge{last, node}]
+	if !ok {
+		panic(fmt.Sprintf("no edge from %d to %d", last, node))
+	}
+	return Path{
+		Length: p.Length + c,
+		Nodes:  append(p.Nodes[0:len(p.Nodes):len(p.Nodes)], node),
+	}
+}
+
+func (b *Burrow) findShortestPaths(src int) []Path {
+	visited := make(map[int]int)
+	type QEntry struct {
+		cost int
+		from int
+		to   int
+	}
+	q := priority_queue.NewFunc(func(a, b QEntry) bool {
+		return a.cost < b.cost
+	})
+	q.Push(QEntry{0, src, src})
+	nodes := b.Cells() + b.Rooms
+	for q.Len() > 0 && len(visited) < nodes {
+		e := q.Pop()
+		if _, ok := visited[e.to]; ok {
+			continue
+		}
+		visited[e.to] = e.from
+		for i := 0; i < nodes; i++ {
+			if _, ok := visited[i]; ok {
+				continue
+			}
+			c, ok := b.edges[Edge{e.to, i}]
+			if !ok {
+				continue
+			}
+			q.Push(QEntry{e.cost + c, e.to, i})
+		}
+	}
+	paths := make([]Path, nodes)
+	paths[src] = Path{
+		Length: 0,
+		Nodes:  []int{src},
+	}
+	delete(visited, src)
+	for len(visited) > 0 {
+		for to, from := range visited {
+			if len(paths[from].Nodes) > 0 {
+				paths[to] = b.appendPath(paths[from], to)
+				delete(visited, to)
+			}
+		}
+	}
+	// Doors can't be occupied, so we don't need to check them.
+	for i, p := range paths {
+		nodes := p.Nodes[:0:0]
+		for _, n := range p.Nodes {
+			if n < b.Cells() {
+				nodes = append(nodes, n)
+			}
+		}
+		paths[i].Nodes = nodes
+	}
+	return paths[:b.Cells()]
+}
+
+// Diagram returns a drawing of s in b.
+func (b *Burrow) Diagram(s State) string {
+	w := new(strings.Builder)
+	first, last := b.Doors[0]-1, b.Doors[len(b.Doors)-1]+1
+	// cell returns the content of column col in row, where row -1 is the
+	// hallway. Columns -1 and b.Hallway are walls.
+	cell := func(row, col int) string {
+		if col < 0 || col >= b.Hallway {
+			return "•"
+		}
+		for r, d := range b.Doors {
+			if d != col {
+				continue
+			}
+			if row < 0 {
+				return " "
+			}
+			return s[b.Cell(r, row)].String()
+		}
+		if row >= 0 {
+			return "•"
+		}
+		for i, c := range b.columns {
+			if c == col {
+				return s[i].String()
+			}
+		}
+		panic("unreachable")
+	}
+	w.WriteString(strings.Repeat("•", b.Hallway+2) + "\n")
+	for row := -1; row < b.Depth; row++ {
+		var l string
+		for col := -1; col <= b.Hallway; col++ {
+			if row > 0 && (col < first || col > last) {
+				l += " "
+				continue
+			}
+			l += cell(row, col)
+		}
+		w.WriteString(strings.TrimRight(l, " ") + "\n")
+	}
+	w.WriteString(strings.Repeat(" ", first+1) + strings.Repeat("•", last-first+1) + "\n")
+	return w.String()
+}
+
+// Dump prints a drawing of s in b.
+func (b *Burrow) Dump(s State) {
+	fmt.Print(b.Diagram(s))
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestStandardBurrow(t *testing.T) {
+	// The edges of the burrow of part 2, as they where written down by hand.
+	edges := map[Edge]int{
+		{0, 1}: 1,
+		{1, 0}: 1, {1, 2}: 2, {1, 7}: 2,
+		{2, 1}: 2, {2, 3}: 2, {2, 7}: 2, {2, 8}: 2,
+		{3, 2}: 2, {3, 4}: 2, {3, 8}: 2, {3, 9}: 2,
+		{4, 3}: 2, {4, 5}: 2, {4, 9}: 2, {4, 10}: 2,
+		{5, 4}: 2, {5, 10}: 2, {5, 6}: 1,
+		{6, 5}: 1,
+		{7, 1}: 2, {7, 2}: 2, {7, 11}: 1,
+		{8, 2}: 2, {8, 3}: 2, {8, 12}: 1,
+		{9, 3}: 2, {9, 4}: 2, {9, 13}: 1,
+		{10, 4}: 2, {10, 5}: 2, {10, 14}: 1,
+		{11, 7}: 1, {11, 15}: 1,
+		{12, 8}: 1, {12, 16}: 1,
+		{13, 9}: 1, {13, 17}: 1,
+		{14, 10}: 1, {14, 18}: 1,
+		{15, 11}: 1, {15, 19}: 1,
+		{16, 12}: 1, {16, 20}: 1,
+		{17, 13}: 1, {17, 21}: 1,
+		{18, 14}: 1, {18, 22}: 1,
+		{19, 15}: 1,
+		{20, 16}: 1,
+		{21, 17}: 1,
+		{22, 18}: 1,
+	}
+	const n = 23
+	var dist [n][n]int
+	for i := range dist {
+		for j := range dist[i] {
+			if i != j {
+				dist[i][j] = 1 << 20
+			}
+		}
+	}
+	for e, c := range edges {
+		dist[e[0]][e[1]] = c
+	}
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if d := dist[i][k] + dist[k][j]; d < dist[i][j] {
+					dist[i][j] = d
+				}
+			}
+		}
+	}
+
+	b := StandardBurrow(4)
+	if b.Cells() != n {
+		t.Fatalf("StandardBurrow(4).Cells() = %d, want %d", b.Cells(), n)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			p := b.Path(i, j)
+			if p.Length != dist[i][j] {
+				t.Errorf("Path(%d, %d).Length = %d, want %d", i, j, p.Length, dist[i][j])
+			}
+			if p.Nodes[0] != i || p.Nodes[len(p.Nodes)-1] != j {
+				t.Errorf("Path(%d, %d).Nodes = %v, want path from %d to %d", i, j, p.Nodes, i, j)
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Explored is a state settled by Organize.
+type Explored struct {
+	State Key
+	// Cost is the energy needed to get from the start to State or, for the
+	// backward part of a bidirectional search, from State to the end.
+	Cost int
+	// Parent is the state State was reached from, via Step. For the start
+	// of a search, it is State.
+	Parent Key
+	Step   Step
+	// Backward is set if State was settled by the backward part of a
+	// bidirectional search. Then, Step leads from State to Parent.
+	Backward bool
+}
+
+// explored returns the states in visited, ordered by cost.
+func explored(visited map[Key]parent, backward bool) []Explored {
+	out := make([]Explored, 0, len(visited))
+	for k, p := range visited {
+		out = append(out, Explored{k, p.cost, p.from, p.step, backward})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Cost != out[j].Cost {
+			return out[i].Cost < out[j].Cost
+		}
+		a, b := out[i].State, out[j].State
+		return a[0] < b[0] || (a[0] == b[0] && a[1] < b[1])
+	})
+	return out
+}
+
+// Compact returns a one-line representation of s, listing the hallway and
+// then the rows of the rooms from top to bottom, separated by slashes.
+func (b *Burrow) Compact(s State) string {
+	w := new(strings.Builder)
+	for i := 0; i < b.Cells(); i++ {
+		if i == len(b.columns) || (i > len(b.columns) && (i-len(b.columns))%b.Rooms == 0) {
+			w.WriteByte('/')
+		}
+		if s[i] == None {
+			w.WriteByte('.')
+		} else {
+			w.WriteString(s[i].String())
+		}
+	}
+	return w.String()
+}
+
+// searchGraph is the graph of states explored by a search, as stored in a
+// Result.
+type searchGraph struct {
+	nodes []graphNode
+	edges []graphEdge
+}
+
+type graphNode struct {
+	id       string
+	cost     int
+	backward bool
+	optimal  bool
+}
+
+type graphEdge struct {
+	from, to string
+	step     Step
+	optimal  bool
+}
+
+// graph returns the graph of the states explored in r, which organizes s. The
+// edges are the ones by which states were settled.
+func (b *Burrow) graph(s State, r Result) (searchGraph, error) {
+	path := map[Key]bool{s.Key(): true}
+	steps := make(map[[2]Key]bool)
+	for _, st := range r.Steps {
+		_, next, err := b.Move(s, st.Src, st.Dst)
+		if err != nil {
+			return searchGraph{}, fmt.Errorf("%v: %w", st, err)
+		}
+		path[next.Key()] = true
+		steps[[2]Key{s.Key(), next.Key()}] = true
+		s = next
+	}
+	var g searchGraph
+	for _, e := range r.Explored {
+		g.nodes = append(g.nodes, graphNode{
+			id:       b.Compact(e.State.State()),
+			cost:     e.Cost,
+			backward: e.Backward,
+			optimal:  path[e.State],
+		})
+		if e.Parent == e.State {
+			continue
+		}
+		from, to := e.Parent, e.State
+		if e.Backward {
+			from, to = to, from
+		}
+		g.edges = append(g.edges, graphEdge{
+			from:    b.Compact(from.State()),
+			to:      b.Compact(to.State()),
+			step:    e.Step,
+			optimal: steps[[2]Key{from, to}],
+		})
+	}
+	return g, nil
+}
+
+// WriteDOT writes the states explored in r, which organizes s, as a Graphviz
+// graph. Nodes are labelled with the state and the energy to reach it and the
+// optimal path is drawn in red.
+func (b *Burrow) WriteDOT(w io.Writer, s State, r Result) error {
+	g, err := b.graph(s, r)
+	if err != nil {
+		return err
+	}
+	buf := new(strings.Builder)
+	buf.WriteString("digraph burrow {\n\tnode [shape=box, fontname=monospace];\n")
+	for _, n := range g.nodes {
+		attr := ""
+		if n.backward {
+			attr += ", style=dashed"
+		}
+		if n.optimal {
+			attr += ", color=red, penwidth=2"
+		}
+		fmt.Fprintf(buf, "\t%q [label=\"%s\\n%d\"%s];\n", n.id, n.id, n.cost, attr)
+	}
+	for _, e := range g.edges {
+		attr := ""
+		if e.optimal {
+			attr = ", color=red, penwidth=2"
+		}
+		fmt.Fprintf(buf, "\t%q -> %q [label=%q%s];\n", e.from, e.to, e.step.String(), attr)
+	}
+	buf.WriteString("}\n")
+	_, err = io.WriteString(w, buf.String())
+	return err
+}
+
+// WriteGEXF writes the states explored in r, which organizes s, as a GEXF
+// graph. Nodes are labelled with the state and have the energy to reach them
+// and whether they are on the optimal path as attributes. The optimal path is
+// colored red.
+func (b *Burrow) WriteGEXF(w io.Writer, s State, r Result) error {
+	g, err := b.graph(s, r)
+	if err != nil {
+		return err
+	}
+	type attribute struct {
+		ID    int    `xml:"id,attr"`
+		Title string `xml:"title,attr"`
+		Type  string `xml:"type,attr"`
+	}
+	type attributes struct {
+		Class     string      `xml:"class,attr"`
+		Attribute []attribute `xml:"attribute"`
+	}
+	type attvalue struct {
+		For   int    `xml:"for,attr"`
+		Value string `xml:"value,attr"`
+	}
+	type color struct {
+		R int `xml:"r,attr"`
+		G int `xml:"g,attr"`
+		B int `xml:"b,attr"`
+	}
+	type node struct {
+		ID        string     `xml:"id,attr"`
+		Label     string     `xml:"label,attr"`
+		Attvalues []attvalue `xml:"attvalues>attvalue"`
+		Color     *color     `xml:"viz:color"`
+	}
+	type edge struct {
+		ID        int        `xml:"id,attr"`
+		Source    string     `xml:"source,attr"`
+		Target    string     `xml:"target,attr"`
+		Weight    int        `xml:"weight,attr"`
+		Label     string     `xml:"label,attr"`
+		Attvalues []attvalue `xml:"attvalues>attvalue"`
+		Color     *color     `xml:"viz:color"`
+	}
+	type graph struct {
+		EdgeType   string       `xml:"defaultedgetype,attr"`
+		Attributes []attributes `xml:"attributes"`
+		Nodes      []node       `xml:"nodes>node"`
+		Edges      []edge       `xml:"edges>edge"`
+	}
+	type gexf struct {
+		XMLName xml.Name `xml:"gexf"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		VizNS   string   `xml:"xmlns:viz,attr"`
+		Version string   `xml:"version,attr"`
+		Graph   graph    `xml:"graph"`
+	}
+	red := &color{255, 0, 0}
+	out := gexf{
+		XMLNS:   "http://gexf.net/1.3",
+		VizNS:   "http://gexf.net/1.3/viz",
+		Version: "1.3",
+		Graph: graph{
+			EdgeType: "directed",
+			Attributes: []attributes{
+				{"node", []attribute{{0, "cost", "integer"}, {1, "optimal", "boolean"}, {2, "backward", "boolean"}}},
+				{"edge", []attribute{{0, "optimal", "boolean"}}},
+			},
+		},
+	}
+	for _, n := range g.nodes {
+		x := node{ID: n.id, Label: fmt.Sprintf("%s %d", n.id, n.cost), Attvalues: []attvalue{
+			{0, fmt.Sprint(n.cost)},
+			{1, fmt.Sprint(n.optimal)},
+			{2, fmt.Sprint(n.backward)},
+		}}
+		if n.optimal {
+			x.Color = red
+		}
+		out.Graph.Nodes = append(out.Graph.Nodes, x)
+	}
+	for i, e := range g.edges {
+		x := edge{ID: i, Source: e.from, Target: e.to, Weight: e.step.Cost, Label: e.step.String(), Attvalues: []attvalue{
+			{0, fmt.Sprint(e.optimal)},
+		}}
+		if e.optimal {
+			x.Color = red
+		}
+		out.Graph.Edges = append(out.Graph.Edges, x)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
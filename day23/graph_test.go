@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWriteGraph(t *testing.T) {
+	b, s := parseFile(t, "input.txt", false)
+	r, err := b.Organize(s, Options{AStar: true, Explore: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Explored) != r.Expanded {
+		t.Errorf("len(Explored) = %d, want %d", len(r.Explored), r.Expanded)
+	}
+
+	dot := new(strings.Builder)
+	if err := b.WriteDOT(dot, s, r); err != nil {
+		t.Fatalf("WriteDOT(…) = %v", err)
+	}
+	var nodes, edges, optNodes, optEdges int
+	for _, l := range strings.Split(dot.String(), "\n") {
+		edge := strings.Contains(l, " -> ")
+		opt := strings.Contains(l, "color=red")
+		switch {
+		case edge && opt:
+			optEdges++
+		case edge:
+			edges++
+		case opt:
+			optNodes++
+		case strings.Contains(l, "[label="):
+			nodes++
+		}
+	}
+	if got := nodes + optNodes; got != r.Expanded {
+		t.Errorf("WriteDOT wrote %d nodes, want %d", got, r.Expanded)
+	}
+	if got := edges + optEdges; got != r.Expanded-1 {
+		t.Errorf("WriteDOT wrote %d edges, want %d", got, r.Expanded-1)
+	}
+	if optNodes != len(r.Steps)+1 || optEdges != len(r.Steps) {
+		t.Errorf("WriteDOT highlighted %d nodes and %d edges, want %d and %d", optNodes, optEdges, len(r.Steps)+1, len(r.Steps))
+	}
+	if want := `"` + b.Compact(b.End()) + `" [label="` + b.Compact(b.End()) + `\n19046", color=red`; !strings.Contains(dot.String(), want) {
+		t.Errorf("WriteDOT does not contain %s", want)
+	}
+
+	gexf := new(strings.Builder)
+	if err := b.WriteGEXF(gexf, s, r); err != nil {
+		t.Fatalf("WriteGEXF(…) = %v", err)
+	}
+	var g struct {
+		Nodes []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"graph>nodes>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+		} `xml:"graph>edges>edge"`
+	}
+	if err := xml.Unmarshal([]byte(gexf.String()), &g); err != nil {
+		t.Fatalf("WriteGEXF wrote invalid XML: %v", err)
+	}
+	if len(g.Nodes) != r.Expanded || len(g.Edges) != r.Expanded-1 {
+		t.Errorf("WriteGEXF wrote %d nodes and %d edges, want %d and %d", len(g.Nodes), len(g.Edges), r.Expanded, r.Expanded-1)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	b, s := parseFile(t, "example.txt", true)
+	if got, want := b.Compact(s), "......./BCBD/DCBA/DBAC/ADCA"; got != want {
+		t.Errorf("Compact(%v) = %q, want %q", s, got, want)
+	}
+}
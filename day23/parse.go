@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Folded are the rows, which are folded into the diagram in part 2.
+var Folded = []string{
+	"  #D#C#B#A#",
+	"  #D#B#A#C#",
+}
+
+// ParseBurrow parses the diagram of a burrow, as given by the puzzle:
+//
+//	#############
+//	#...........#
+//	###B#C#B#D###
+//	  #A#D#C#A#
+//	  #########
+//
+// The topology of the burrow is derived from the diagram, so it can have any
+// number of rooms of any depth.
+//
+// If unfold is set, the rows in Folded are inserted between the two rows of
+// the rooms, as in part 2. This requires the burrow of the puzzle.
+func ParseBurrow(r io.Reader, unfold bool) (*Burrow, State, error) {
+	var lines []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if l := strings.TrimRight(s.Text(), " "); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, State{}, err
+	}
+	if len(lines) < 4 {
+		return nil, State{}, fmt.Errorf("diagram has %d lines, want at least 4", len(lines))
+	}
+	hallway := lines[1]
+	if len(hallway) < 3 || hallway[0] != '#' || hallway[len(hallway)-1] != '#' {
+		return nil, State{}, fmt.Errorf("invalid hallway %q", hallway)
+	}
+	if lines[0] != strings.Repeat("#", len(hallway)) {
+		return nil, State{}, errors.New("hallway is not enclosed by walls")
+	}
+	if strings.Trim(lines[len(lines)-1], "# ") != "" {
+		return nil, State{}, errors.New("rooms are not enclosed by walls")
+	}
+	rows := lines[2 : len(lines)-1]
+
+	var doors []int
+	for col := 1; col < len(hallway)-1 && col < len(rows[0]); col++ {
+		if rows[0][col] != '#' {
+			doors = append(doors, col-1)
+		}
+	}
+	if len(doors) == 0 {
+		return nil, State{}, errors.New("burrow has no rooms")
+	}
+	if unfold {
+		if len(doors) != 4 || len(rows) != 2 || doors[0] != 2 || doors[1] != 4 || doors[2] != 6 || doors[3] != 8 {
+			return nil, State{}, errors.New("can only unfold the burrow of the puzzle")
+		}
+		rows = []string{rows[0], Folded[0], Folded[1], rows[1]}
+	}
+	b, err := NewBurrow(len(doors), len(rows), len(hallway)-2, doors)
+	if err != nil {
+		return nil, State{}, err
+	}
+
+	var (
+		st    State
+		count [maxCell + 1]int
+	)
+	set := func(i int, ch byte) error {
+		c, err := parseCell(ch)
+		if err != nil {
+			return err
+		}
+		if c > A+Cell(b.Rooms-1) {
+			return fmt.Errorf("amphipod %v has no room", c)
+		}
+		st[i] = c
+		count[c]++
+		return nil
+	}
+	for i, col := range b.columns {
+		if err := set(i, hallway[col+1]); err != nil {
+			return nil, State{}, fmt.Errorf("hallway: %w", err)
+		}
+	}
+	for _, d := range doors {
+		if hallway[d+1] != '.' {
+			return nil, State{}, fmt.Errorf("amphipod %c stands in front of a room", hallway[d+1])
+		}
+	}
+	for i, row := range rows {
+		for col := 1; col < len(hallway)-1; col++ {
+			var ch byte = ' '
+			if col < len(row) {
+				ch = row[col]
+			}
+			if ch == '#' || ch == ' ' {
+				continue
+			}
+			r := roomAt(doors, col-1)
+			if r < 0 {
+				return nil, State{}, fmt.Errorf("room row %d: unexpected %q in column %d", i, ch, col)
+			}
+			if err := set(b.Cell(r, i), ch); err != nil {
+				return nil, State{}, fmt.Errorf("room row %d: %w", i, err)
+			}
+		}
+		for r, d := range doors {
+			if d+1 >= len(row) || row[d+1] == '#' || row[d+1] == ' ' {
+				return nil, State{}, fmt.Errorf("room row %d: room %d is missing", i, r)
+			}
+		}
+	}
+	for r := 0; r < b.Rooms; r++ {
+		if c := A + Cell(r); count[c] != b.Depth {
+			return nil, State{}, fmt.Errorf("there are %d amphipods of type %v, want %d", count[c], c, b.Depth)
+		}
+	}
+	return b, st, nil
+}
+
+// roomAt returns the room with its door in column col, or -1.
+func roomAt(doors []int, col int) int {
+	for r, d := range doors {
+		if d == col {
+			return r
+		}
+	}
+	return -1
+}
+
+func parseCell(b byte) (Cell, error) {
+	if b == '.' {
+		return None, nil
+	}
+	if b < 'A' || b > 'A'+byte(maxCell-A) {
+		return None, fmt.Errorf("invalid cell %q", b)
+	}
+	return A + Cell(b-'A'), nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseBurrow(t *testing.T) {
+	tcs := []struct {
+		file string
+		want State
+	}{
+		{"example.txt", State{
+			7: B, 8: C, 9: B, 10: D,
+			11: D, 12: C, 13: B, 14: A,
+			15: D, 16: B, 17: A, 18: C,
+			19: A, 20: D, 21: C, 22: A,
+		}},
+		{"input.txt", State{
+			7: D, 8: A, 9: C, 10: C,
+			11: D, 12: C, 13: B, 14: A,
+			15: D, 16: B, 17: A, 18: C,
+			19: D, 20: A, 21: B, 22: B,
+		}},
+	}
+	for _, tc := range tcs {
+		f, err := os.Open(tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, got, err := ParseBurrow(f, true)
+		f.Close()
+		if err != nil {
+			t.Fatalf("ParseBurrow(%q, true) = _, _, %v, want <nil>", tc.file, err)
+		}
+		if b.Rooms != 4 || b.Depth != 4 || b.Hallway != 11 {
+			t.Errorf("ParseBurrow(%q, true) = %+v, _, _, want 4 rooms of depth 4 and a hallway of length 11", tc.file, b)
+		}
+		if got != tc.want {
+			t.Errorf("ParseBurrow(%q, true) = _, %v, _, want %v", tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestParseBurrowCustom(t *testing.T) {
+	diagram := `
+###########
+#.A.......#
+###.#B#C###
+  #B#A#C#
+  #A#C#B#
+  #######
+`
+	b, s, err := ParseBurrow(strings.NewReader(diagram), false)
+	if err != nil {
+		t.Fatalf("ParseBurrow(…) = _, _, %v, want <nil>", err)
+	}
+	if b.Rooms != 3 || b.Depth != 3 || b.Hallway != 9 {
+		t.Fatalf("ParseBurrow(…) = %+v, want 3 rooms of depth 3 and a hallway of length 9", b)
+	}
+	want := State{
+		1: A,
+		6: None, 7: B, 8: C,
+		9: B, 10: A, 11: C,
+		12: A, 13: C, 14: B,
+	}
+	if s != want {
+		t.Errorf("ParseBurrow(…) = _, %v, _, want %v", s, want)
+	}
+	wantDiagram := "•••••••••••\n• A       •\n••• •B•C•••\n  •B•A•C•\n  •A•C•B•\n  •••••••\n"
+	if got := b.Diagram(s); got != wantDiagram {
+		t.Errorf("Diagram(…) =\n%s\nwant\n%s", got, wantDiagram)
+	}
+}
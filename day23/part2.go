@@ -1,36 +1,111 @@
-// Occupiable positions are numbered top-to-bottom, left-to-right:
-// •••••••••••••••••••
-// •0 1. 2. 3. 4. 5 6•
-// ••••7 •8 •9 •10••••
-//    •11•12•13•14•
-//    •15•16•17•18•
-//    •19•20•21•22•
-//    •••••••••••••
-
 package main
 
 import (
+	"bytes"
 	"errors"
+	"flag"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/Merovius/aoc_2021/go/priority_queue"
 )
 
 func main() {
-	var example = State{
-		7: B, 8: C, 9: B, 10: D,
-		11: D, 12: C, 13: B, 14: A,
-		15: D, 16: B, 17: A, 18: C,
-		19: A, 20: D, 21: C, 22: A,
-	}
-	var input = State{
-		7: D, 8: A, 9: C, 10: C,
-		11: D, 12: C, 13: B, 14: A,
-		15: D, 16: B, 17: A, 18: C,
-		19: D, 20: A, 21: B, 22: B,
-	}
-	_, _ = example, input
-	fmt.Println("Cost to organize:", Organize(input))
+	file := flag.String("input", "input.txt", "file containing the diagram of the burrow")
+	var opts Options
+	flag.BoolVar(&opts.AStar, "astar", true, "use A* instead of Dijkstra's algorithm")
+	flag.BoolVar(&opts.Progress, "progress", false, "print the state of the search every 1000 energy")
+	replay := flag.Bool("replay", false, "print the states of the burrow while replaying the solution")
+	flag.BoolVar(&opts.Bidirectional, "bidi", false, "search forward and backward at the same time")
+	flag.IntVar(&opts.Workers, "workers", 1, "number of goroutines expanding states in a bidirectional search (0 means one per CPU)")
+	graph := flag.String("graph", "", "write the states explored by the search to this file (with .unfolded inserted for the unfolded burrow)")
+	graphFormat := flag.String("graphformat", "dot", "format of the graph written with -graph (dot or gexf)")
+	interactive := flag.Bool("interactive", false, "organize the burrow interactively")
+	unfold := flag.Bool("unfold", false, "unfold the burrow in interactive mode")
+	flag.Parse()
+
+	buf, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *interactive {
+		b, s, err := ParseBurrow(bytes.NewReader(buf), *unfold)
+		if err != nil {
+			log.Fatalf("%s: %v", *file, err)
+		}
+		if err := b.REPL(s, opts, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *graph != "" {
+		opts.Explore = true
+		if *graphFormat != "dot" && *graphFormat != "gexf" {
+			log.Fatalf("invalid graph format %q", *graphFormat)
+		}
+	}
+	solve := func(unfold bool) *Burrow {
+		b, s, err := ParseBurrow(bytes.NewReader(buf), unfold)
+		if err != nil {
+			log.Fatalf("%s: %v", *file, err)
+		}
+		r, err := b.Organize(s, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *replay {
+			if err := b.Replay(s, r.Steps); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *graph != "" {
+			if err := writeGraph(graphFile(*graph, unfold), *graphFormat, b, s, r); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if unfold {
+			fmt.Printf("Cost to organize unfolded burrow: %d (%d states expanded)\n", r.Energy, r.Expanded)
+		} else {
+			fmt.Printf("Cost to organize: %d (%d states expanded)\n", r.Energy, r.Expanded)
+		}
+		return b
+	}
+	b := solve(false)
+	// The folded rows only fit the burrow of the puzzle.
+	if b.Rooms == 4 && b.Depth == 2 {
+		solve(true)
+	}
+}
+
+// graphFile returns the file to write the graph of the search to. For the
+// unfolded burrow, ".unfolded" is inserted before the extension of name.
+func graphFile(name string, unfold bool) string {
+	if !unfold {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + ".unfolded" + ext
+}
+
+// writeGraph writes the states explored in r to the given file, in the given
+// format.
+func writeGraph(file, format string, b *Burrow, s State, r Result) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	write := b.WriteDOT
+	if format == "gexf" {
+		write = b.WriteGEXF
+	}
+	if err := write(f, s, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
 // Cell is a cell content
@@ -43,271 +118,374 @@ const (
 	B
 	C
 	D
+	E
+	F
+	G
+
+	maxCell = G
 )
 
-// State is a full state of the burrow.
-type State [23]Cell
+// State is a full state of the burrow. Cells beyond the size of the burrow are
+// always None.
+type State [MaxCells]Cell
 
-var End = State{
-	7: A, 8: B, 9: C, 10: D,
-	11: A, 12: B, 13: C, 14: D,
-	15: A, 16: B, 17: C, 18: D,
-	19: A, 20: B, 21: C, 22: D,
-}
+// Key is a compact encoding of a State, using 3 bits per cell.
+type Key [2]uint64
 
-// Edge is an edge between two cells
-type Edge [2]int
-
-// EdgeCost is the cost associated with the given edge. If an edge is not in
-// EdgeCost, it doesn't exist.
-var EdgeCost = map[Edge]int{
-	{0, 1}: 1,
-	{1, 0}: 1, {1, 2}: 2, {1, 7}: 2,
-	{2, 1}: 2, {2, 3}: 2, {2, 7}: 2, {2, 8}: 2,
-	{3, 2}: 2, {3, 4}: 2, {3, 8}: 2, {3, 9}: 2,
-	{4, 3}: 2, {4, 5}: 2, {4, 9}: 2, {4, 10}: 2,
-	{5, 4}: 2, {5, 10}: 2, {5, 6}: 1,
-	{6, 5}: 1,
-	{7, 1}: 2, {7, 2}: 2, {7, 11}: 1,
-	{8, 2}: 2, {8, 3}: 2, {8, 12}: 1,
-	{9, 3}: 2, {9, 4}: 2, {9, 13}: 1,
-	{10, 4}: 2, {10, 5}: 2, {10, 14}: 1,
-	{11, 7}: 1, {11, 15}: 1,
-	{12, 8}: 1, {12, 16}: 1,
-	{13, 9}: 1, {13, 17}: 1,
-	{14, 10}: 1, {14, 18}: 1,
-	{15, 11}: 1, {15, 19}: 1,
-	{16, 12}: 1, {16, 20}: 1,
-	{17, 13}: 1, {17, 21}: 1,
-	{18, 14}: 1, {18, 22}: 1,
-	{19, 15}: 1,
-	{20, 16}: 1,
-	{21, 17}: 1,
-	{22, 18}: 1,
-}
+// cellsPerWord is the number of cells encoded in each word of a Key.
+const cellsPerWord = 21
 
-type Path struct {
-	Length int
-	Nodes  []int
+// Key returns the compact encoding of s.
+func (s State) Key() Key {
+	var k Key
+	for i, c := range s {
+		k[i/cellsPerWord] |= uint64(c) << (3 * (i % cellsPerWord))
+	}
+	return k
 }
 
-var Paths [23][23]Path
+// State returns the State encoded by k.
+func (k Key) State() State {
+	var s State
+	for i := range s {
+		s[i] = Cell(k[i/cellsPerWord]>>(3*(i%cellsPerWord))) & 7
+	}
+	return s
+}
 
-func (p Path) Append(node int) Path {
-	last := p.Nodes[len(p.Nodes)-1]
-	c, ok := EdgeCost[Edge{last, node}]
-	if !ok {
-		panic(fmt.Sprintf("no edge from %d to %d", last, node))
+func (c Cell) Cost() int {
+	if c == None || c > maxCell {
+		panic("invalid cell")
 	}
-	return Path{
-		Length: p.Length + c,
-		Nodes:  append(p.Nodes[0:len(p.Nodes):len(p.Nodes)], node),
+	cost := 1
+	for ; c > A; c-- {
+		cost *= 10
 	}
+	return cost
 }
 
-func findShortestPaths(src int) {
-	visited := make(map[int]int)
-	type QEntry struct {
-		cost int
-		from int
-		to   int
+func (c Cell) String() string {
+	if c == None || c > maxCell {
+		return " "
 	}
-	q := priority_queue.NewFunc(func(a, b QEntry) bool {
-		return a.cost < b.cost
-	})
-	q.Push(QEntry{0, src, src})
-	for q.Len() > 0 && len(visited) < 23 {
-		e := q.Pop()
-		if _, ok := visited[e.to]; ok {
-			continue
-		}
-		visited[e.to] = e.from
-		for i := 0; i < 23; i++ {
-			if _, ok := visited[i]; ok {
-				continue
-			}
-			c, ok := EdgeCost[Edge{e.to, i}]
-			if !ok {
-				continue
-			}
-			q.Push(QEntry{e.cost + c, e.to, i})
-		}
+	return string(rune('A' + c - A))
+}
+
+// Errors returned by Move.
+var (
+	errUnoccupied = errors.New("source cell is unoccupied")
+	errOccupied   = errors.New("destination cell is occupied")
+	errHome       = errors.New("amphipod is already home")
+	errHallway    = errors.New("amphipod stands in hallway and can only move home")
+	errRoom       = errors.New("amphipod can only move home or into hallway")
+	errStranger   = errors.New("amphipods home is occupied by wrong amphipod type")
+	errDeepest    = errors.New("amphipod must move to the deepest free cell of its home")
+	errBlocked    = errors.New("path is blocked")
+)
+
+// Move moves the amphipod at src to dst and returns the energy used and the
+// resulting state. It returns an error, if the move violates the rules:
+//
+//   - Amphipods never stop in front of a door. This is guaranteed by not
+//     numbering these cells.
+//   - Amphipods only move from a room into the hallway, or into their home.
+//   - Amphipods only enter their home if it contains no amphipods of another
+//     type, and then move to its deepest free cell.
+//   - Amphipods which are home with only amphipods of the same type below
+//     them never move again.
+func (b *Burrow) Move(s State, src, dst int) (cost int, next State, err error) {
+	if s[src] == None {
+		return 0, State{}, errUnoccupied
 	}
-	Paths[src][src] = Path{
-		Length: 0,
-		Nodes:  []int{src},
+	if s[dst] != None {
+		return 0, State{}, errOccupied
 	}
-	delete(visited, src)
-	for len(visited) > 0 {
-		for to, from := range visited {
-			if len(Paths[src][from].Nodes) > 0 {
-				Paths[src][to] = Paths[src][from].Append(to)
-				delete(visited, to)
-			}
+	a := s[src]
+	if b.settled(s, src) {
+		return 0, State{}, errHome
+	}
+	if !b.IsHome(a, dst) {
+		if b.IsHallway(src) {
+			return 0, State{}, errHallway
+		}
+		if !b.IsHallway(dst) {
+			return 0, State{}, errRoom
 		}
+	} else if t := b.target(s, a); t < 0 {
+		return 0, State{}, errStranger
+	} else if dst != t {
+		return 0, State{}, errDeepest
 	}
-}
-
-func init() {
-	for i := 0; i < 23; i++ {
-		findShortestPaths(i)
+	if !b.clear(s, src, dst) {
+		return 0, State{}, errBlocked
 	}
+	s[src], s[dst] = None, a
+	return a.Cost() * b.Path(src, dst).Length, s, nil
 }
 
-// CellCost are the energy used by amphipod type.
-var CellCost = [...]int{
-	A: 1,
-	B: 10,
-	C: 100,
-	D: 1000,
+// clear returns whether the path from src to dst is unoccupied in s.
+func (b *Burrow) clear(s State, src, dst int) bool {
+	for _, n := range b.Path(src, dst).Nodes[1:] {
+		if s[n] != None {
+			return false
+		}
+	}
+	return true
 }
 
-// Homes are the home-cells for each type of amphipod.
-var Homes = map[Cell][4]int{
-	A: {7, 11, 15, 19},
-	B: {8, 12, 16, 20},
-	C: {9, 13, 17, 21},
-	D: {10, 14, 18, 22},
+// settled returns whether the amphipod at i is home, with only amphipods of the
+// same type below it.
+func (b *Burrow) settled(s State, i int) bool {
+	c := s[i]
+	if c == None || !b.IsHome(c, i) {
+		return false
+	}
+	for j := i + b.Rooms; j < b.Cells(); j += b.Rooms {
+		if s[j] != c {
+			return false
+		}
+	}
+	return true
 }
 
-func (c Cell) IsHome(i int) bool {
-	for _, h := range Homes[c] {
-		if h == i {
-			return true
+// target returns the deepest free cell in the home of amphipods of type c, or
+// -1 if they can't enter it.
+func (b *Burrow) target(s State, c Cell) int {
+	t := -1
+	for row := 0; row < b.Depth; row++ {
+		switch i := b.Cell(int(c-A), row); s[i] {
+		case None:
+			t = i
+		case c:
+		default:
+			return -1
 		}
 	}
-	return false
+	return t
 }
 
-func (c Cell) Cost() int {
-	switch c {
-	case A:
-		return 1
-	case B:
-		return 10
-	case C:
-		return 100
-	case D:
-		return 1000
-	default:
-		panic("invalid cell")
-	}
+// Step is a move of an amphipod from Src to Dst, using Cost energy.
+type Step struct {
+	Src  int
+	Dst  int
+	Cost int
 }
 
-func (c Cell) String() string {
-	switch c {
-	case A:
-		return "A"
-	case B:
-		return "B"
-	case C:
-		return "C"
-	case D:
-		return "D"
-	default:
-		return " "
-	}
+func (s Step) String() string {
+	return fmt.Sprintf("%d → %d (%d energy)", s.Src, s.Dst, s.Cost)
 }
 
-func IsHallway(i int) bool {
-	return i < 7
+type Neighbor struct {
+	Step  Step
+	State State
 }
 
-func (s State) Move(src, dst int) (cost int, next State, err error) {
-	if s[src] == None {
-		return 0, State{}, errors.New("source cell is unoccupied")
-	}
-	if s[dst] != None {
-		return 0, State{}, errors.New("destination cell is occupied")
-	}
-	a := s[src]
-	if !a.IsHome(dst) {
-		if IsHallway(src) {
-			return 0, State{}, errors.New("amphipod stands in hallway and can only move home")
+// Moves returns all legal moves in s, ordered by source and destination.
+func (b *Burrow) Moves(s State) []Neighbor {
+	var out []Neighbor
+	add := func(src, dst int) {
+		if s[dst] != None || !b.clear(s, src, dst) {
+			return
 		}
-		if !IsHallway(dst) {
-			return 0, State{}, errors.New("amphipod can only move home or into hallway")
+		n := s
+		n[src], n[dst] = None, s[src]
+		out = append(out, Neighbor{Step{src, dst, s[src].Cost() * b.Path(src, dst).Length}, n})
+	}
+	for src := 0; src < b.Cells(); src++ {
+		a := s[src]
+		if a == None || b.settled(s, src) {
+			continue
 		}
-	} else {
-		for _, h := range Homes[a] {
-			if s[h] != None && s[h] != a {
-				return 0, State{}, errors.New("amphipods home is occupied by wrong amphipod type")
+		// Amphipods below others can't leave their room.
+		if !b.IsHallway(src) && (src < b.Cell(0, 1) || s[src-b.Rooms] == None) {
+			for dst := range b.columns {
+				add(src, dst)
 			}
 		}
-	}
-	p := Paths[src][dst]
-	for _, n := range p.Nodes[1:] {
-		if s[n] != None {
-			return 0, State{}, errors.New("path is blocked")
+		if t := b.target(s, a); t >= 0 {
+			add(src, t)
 		}
 	}
-	s[src], s[dst] = None, a
-	return a.Cost() * p.Length, s, nil
+	return out
 }
 
-type Neighbor struct {
-	Cost  int
-	State State
+// Neighbors returns the moves in s which are worth considering when searching
+// for an optimal solution.
+//
+// If an amphipod can move home, that is the only neighbor: It has to get there
+// eventually, no other path is shorter and once home, it is never in the way.
+func (b *Burrow) Neighbors(s State) []Neighbor {
+	ms := b.Moves(s)
+	for i, m := range ms {
+		if !b.IsHallway(m.Step.Dst) {
+			return ms[i : i+1]
+		}
+	}
+	return ms
 }
 
-func (s State) Neighbors() []Neighbor {
-	var out []Neighbor
-	for i := 0; i < 23; i++ {
-		for j := 0; j < 23; j++ {
-			c, s, err := s.Move(i, j)
-			if err != nil {
+// Heuristic returns a lower bound for the energy needed to organize s.
+//
+// An amphipod is settled, if it is in its home and no amphipod of a different
+// type is below it. Every amphipod which is not settled has to walk at least
+// to the top of its home. Then, all cells in a home which are not occupied by
+// a settled amphipod have to be filled, by walking down from the top of the
+// room or from a cell further up. Every move decreases the bound by at most
+// its cost, so the bound can be used for A*.
+func (b *Burrow) Heuristic(s State) int {
+	var (
+		h       int
+		settled [MaxCells]bool
+	)
+	for r := 0; r < b.Rooms; r++ {
+		c := A + Cell(r)
+		stranger := false
+		for row := b.Depth - 1; row >= 0; row-- {
+			i := b.Cell(r, row)
+			if s[i] == c && !stranger {
+				settled[i] = true
 				continue
 			}
-			out = append(out, Neighbor{c, s})
+			if s[i] != None && s[i] != c {
+				stranger = true
+			}
+			h += c.Cost() * row
 		}
 	}
-	return out
+	for i := 0; i < b.Cells(); i++ {
+		if s[i] != None && !settled[i] {
+			h += s[i].Cost() * b.enter[i][s[i]-A]
+		}
+	}
+	return h
+}
+
+// Options configure Organize.
+type Options struct {
+	// AStar enables guiding the search by Heuristic. Otherwise, Organize
+	// uses Dijkstra's algorithm.
+	AStar bool
+	// Progress enables printing the state of the search every 1000
+	// energy.
+	Progress bool
+	// Bidirectional enables searching forward from the start and backward
+	// from the end at the same time. Progress is ignored.
+	Bidirectional bool
+	// Workers is the number of goroutines expanding states in a
+	// bidirectional search. If it is 0, one per CPU is used.
+	Workers int
+	// Explore enables recording the settled states in Result.Explored.
+	Explore bool
 }
 
-func (s State) Dump() {
-	fmt.Println("•••••••••••••")
-	fmt.Printf("•%v%v %v %v %v %v%v•\n", s[0], s[1], s[2], s[3], s[4], s[5], s[6])
-	fmt.Printf("•••%v•%v•%v•%v•••\n", s[7], s[8], s[9], s[10])
-	fmt.Printf("  •%v•%v•%v•%v•\n", s[11], s[12], s[13], s[14])
-	fmt.Printf("  •%v•%v•%v•%v•\n", s[15], s[16], s[17], s[18])
-	fmt.Printf("  •%v•%v•%v•%v•\n", s[19], s[20], s[21], s[22])
-	fmt.Println("  •••••••••")
+// Result is the result of Organize.
+type Result struct {
+	// Energy is the minimal energy needed to organize the burrow.
+	Energy int
+	// Steps are the moves needed to organize the burrow.
+	Steps []Step
+	// Expanded is the number of states expanded by the search.
+	Expanded int
+	// Explored are the states settled by the search, if enabled by
+	// Options.Explore. They are also set if the burrow can't be organized.
+	Explored []Explored
 }
 
-func Organize(s State) int {
+// ErrNoSolution is returned by Organize, if a burrow can't be organized.
+var ErrNoSolution = errors.New("burrow can not be organized")
+
+// Organize finds the cheapest way to organize s.
+func (b *Burrow) Organize(s State, opts Options) (Result, error) {
+	if opts.Bidirectional {
+		return b.organizeBidirectional(s, opts)
+	}
 	type QEntry struct {
 		prio int
 		cost int
-		from State
-		to   State
+		from Key
+		step Step
+		to   Key
 	}
 	q := priority_queue.NewFunc(func(a, b QEntry) bool {
 		return a.prio < b.prio
 	})
-	visited := make(map[State]State)
-	q.Push(QEntry{0, 0, s, s})
+	prio := func(cost int, s State) int {
+		if opts.AStar {
+			return cost + b.Heuristic(s)
+		}
+		return cost
+	}
+	start, end := s.Key(), b.End().Key()
+	visited := make(map[Key]parent)
+	q.Push(QEntry{prio(0, s), 0, start, Step{}, start})
 	update := 1000
 	for q.Len() > 0 {
 		e := q.Pop()
 		if _, ok := visited[e.to]; ok {
 			continue
 		}
-		visited[e.to] = e.from
-		if e.prio > update {
-			e.to.Dump()
+		visited[e.to] = parent{e.cost, e.from, e.step}
+		if opts.Progress && e.prio > update {
+			b.Dump(e.to.State())
 			fmt.Println(e.prio)
 			update += 1000
 		}
-		if e.to == End {
-			return e.prio
-		}
-		for _, n := range e.to.Neighbors() {
-			if _, ok := visited[n.State]; ok {
-				continue
+		if e.to != end {
+			for _, n := range b.Neighbors(e.to.State()) {
+				k := n.State.Key()
+				if _, ok := visited[k]; ok {
+					continue
+				}
+				cost := e.cost + n.Step.Cost
+				q.Push(QEntry{prio(cost, n.State), cost, e.to, n.Step, k})
 			}
-			cost := e.cost + n.Cost
-			q.Push(QEntry{cost, cost, e.to, n.State})
+			continue
+		}
+		r := Result{Energy: e.cost, Expanded: len(visited)}
+		if opts.Explore {
+			r.Explored = explored(visited, false)
+		}
+		for t := end; t != start; {
+			p := visited[t]
+			r.Steps = append(r.Steps, p.step)
+			t = p.from
+		}
+		for i, j := 0, len(r.Steps)-1; i < j; i, j = i+1, j-1 {
+			r.Steps[i], r.Steps[j] = r.Steps[j], r.Steps[i]
+		}
+		return r, nil
+	}
+	r := Result{Expanded: len(visited)}
+	if opts.Explore {
+		r.Explored = explored(visited, false)
+	}
+	return r, ErrNoSolution
+}
+
+// parent is the state a settled state was reached from by a search, together
+// with the energy used to get there.
+type parent struct {
+	cost int
+	from Key
+	step Step
+}
+
+// Replay prints every intermediate state when executing steps, starting from
+// s, together with the energy used so far.
+func (b *Burrow) Replay(s State, steps []Step) error {
+	b.Dump(s)
+	fmt.Println(0)
+	var energy int
+	for _, st := range steps {
+		c, next, err := b.Move(s, st.Src, st.Dst)
+		if err != nil {
+			return fmt.Errorf("%v: %w", st, err)
 		}
+		s, energy = next, energy+c
+		fmt.Println()
+		fmt.Println(st)
+		b.Dump(s)
+		fmt.Println(energy)
 	}
-	panic("no solution found")
+	return nil
 }
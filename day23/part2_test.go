@@ -0,0 +1,327 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseFile(tb testing.TB, file string, unfold bool) (*Burrow, State) {
+	tb.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	b, s, err := ParseBurrow(f, unfold)
+	if err != nil {
+		tb.Fatalf("ParseBurrow(%q, %v) = _, _, %v, want <nil>", file, unfold, err)
+	}
+	return b, s
+}
+
+func TestOrganize(t *testing.T) {
+	tcs := []struct {
+		file     string
+		unfold   bool
+		dijkstra bool
+		slow     bool
+		want     int
+	}{
+		{"example.txt", false, true, false, 12521},
+		{"example.txt", true, false, true, 44169},
+		{"input.txt", false, false, false, 19046},
+		{"input.txt", true, false, false, 47484},
+	}
+	for _, tc := range tcs {
+		if tc.slow && testing.Short() {
+			continue
+		}
+		b, s := parseFile(t, tc.file, tc.unfold)
+		r, err := b.Organize(s, Options{AStar: true})
+		if err != nil || r.Energy != tc.want {
+			t.Errorf("Organize(%q, unfold=%v, A*) = %d, %v, want %d, <nil>", tc.file, tc.unfold, r.Energy, err, tc.want)
+		}
+		checkSteps(t, b, s, r)
+		for _, astar := range []bool{false, true} {
+			for _, workers := range []int{1, 4} {
+				r, err := b.Organize(s, Options{AStar: astar, Bidirectional: true, Workers: workers})
+				if err != nil || r.Energy != tc.want {
+					t.Errorf("Organize(%q, unfold=%v, bidirectional, A*=%v, %d workers) = %d, %v, want %d, <nil>", tc.file, tc.unfold, astar, workers, r.Energy, err, tc.want)
+				}
+				checkSteps(t, b, s, r)
+			}
+		}
+		if !tc.dijkstra {
+			continue
+		}
+		if got, err := b.Organize(s, Options{}); err != nil || got.Energy != tc.want {
+			t.Errorf("Organize(%q, unfold=%v, Dijkstra) = %d, %v, want %d, <nil>", tc.file, tc.unfold, got.Energy, err, tc.want)
+		}
+	}
+}
+
+// checkSteps checks that r.Steps are legal, organize s and use r.Energy.
+func checkSteps(t *testing.T, b *Burrow, s State, r Result) {
+	t.Helper()
+	var energy int
+	for _, st := range r.Steps {
+		c, next, err := b.Move(s, st.Src, st.Dst)
+		if err != nil {
+			t.Errorf("Move(%v, %d, %d) = %v", s, st.Src, st.Dst, err)
+			return
+		}
+		if c != st.Cost {
+			t.Errorf("Move(%v, %d, %d) costs %d, but step %v claims %d", s, st.Src, st.Dst, c, st, st.Cost)
+		}
+		s, energy = next, energy+c
+	}
+	if s != b.End() {
+		t.Errorf("steps end in %v, want %v", s, b.End())
+	}
+	if energy != r.Energy {
+		t.Errorf("steps use %d energy, want %d", energy, r.Energy)
+	}
+}
+
+// smallBurrow is a burrow with few enough reachable states to check all of
+// them.
+const smallBurrow = `
+#########
+#.......#
+###B#C#A#
+  #C#A#B#
+  #######
+`
+
+// reachable calls f on all states reachable from s.
+func reachable(b *Burrow, s State, f func(State)) {
+	visited := map[State]bool{s: true}
+	states := []State{s}
+	for len(states) > 0 {
+		s := states[len(states)-1]
+		states = states[:len(states)-1]
+		f(s)
+		for _, n := range b.Moves(s) {
+			if !visited[n.State] {
+				visited[n.State] = true
+				states = append(states, n.State)
+			}
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	var s State
+	for i := range s {
+		s[i] = Cell(i*5) % (maxCell + 1)
+	}
+	for _, s := range []State{{}, s, StandardBurrow(4).End()} {
+		if got := s.Key().State(); got != s {
+			t.Errorf("%v.Key().State() = %v", s, got)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	// In the burrow of part 1, the hallway has positions 0-6, the tops of
+	// the rooms are 7-10 and their bottoms 11-14.
+	tcs := []struct {
+		diagram  string
+		src, dst int
+		want     int
+		err      string
+	}{
+		{example, 0, 1, 0, "source cell is unoccupied"},
+		{example, 7, 11, 0, "destination cell is occupied"},
+		{example, 7, 1, 20, ""},
+		{example, 7, 0, 30, ""},
+		{example, 9, 4, 20, ""},
+		{example, 11, 0, 0, "amphipod is already home"},
+		{example, 13, 3, 0, "amphipod is already home"},
+		{example, 12, 3, 0, "path is blocked"},
+		{blocked, 3, 5, 0, "amphipod stands in hallway and can only move home"},
+		{blocked, 3, 10, 0, "amphipods home is occupied by wrong amphipod type"},
+		{blocked, 14, 0, 0, "path is blocked"},
+		{blocked, 14, 5, 3, ""},
+		{blocked, 8, 10, 0, "amphipod can only move home or into hallway"},
+		{deepest, 1, 7, 0, "amphipod must move to the deepest free cell of its home"},
+		{deepest, 1, 11, 3, ""},
+		{deepest, 2, 11, 3, ""},
+		{deepest, 8, 0, 0, "amphipod is already home"},
+		{deepest, 12, 0, 0, "amphipod is already home"},
+		{floating, 7, 11, 1, ""},
+		{floating, 7, 2, 2, ""},
+	}
+	for _, tc := range tcs {
+		b, s, err := ParseBurrow(strings.NewReader(tc.diagram), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := b.Move(s, tc.src, tc.dst)
+		if (err == nil) != (tc.err == "") || (err != nil && err.Error() != tc.err) {
+			t.Errorf("Move(%v, %d, %d) = _, _, %v, want %q\n%s", s, tc.src, tc.dst, err, tc.err, b.Diagram(s))
+		} else if got != tc.want {
+			t.Errorf("Move(%v, %d, %d) = %d, want %d\n%s", s, tc.src, tc.dst, got, tc.want, b.Diagram(s))
+		}
+	}
+}
+
+const (
+	example = `
+#############
+#...........#
+###B#C#B#D###
+  #A#D#C#A#
+  #########
+`
+	blocked = `
+#############
+#.....D.....#
+###B#C#B#.###
+  #A#D#C#A#
+  #########
+`
+	deepest = `
+#############
+#.A.A.......#
+###.#B#C#D###
+  #.#B#C#D#
+  #########
+`
+	floating = `
+#############
+#.A.........#
+###A#B#C#D###
+  #.#B#C#D#
+  #########
+`
+)
+
+func TestMoves(t *testing.T) {
+	b, s, err := ParseBurrow(strings.NewReader(smallBurrow), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reachable(b, s, func(s State) {
+		var want []Neighbor
+		for i := 0; i < b.Cells(); i++ {
+			for j := 0; j < b.Cells(); j++ {
+				if c, next, err := b.Move(s, i, j); err == nil {
+					want = append(want, Neighbor{Step{i, j, c}, next})
+				}
+			}
+		}
+		got := b.Moves(s)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Moves(%v) = %v, want %v", s, got, want)
+		}
+		// Neighbors only prunes, if an amphipod can move home.
+		for _, n := range got {
+			if !b.IsHallway(n.Step.Dst) {
+				want = []Neighbor{n}
+				break
+			}
+		}
+		if got := b.Neighbors(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("Neighbors(%v) = %v, want %v", s, got, want)
+		}
+	})
+}
+
+func TestPredecessors(t *testing.T) {
+	b, s, err := ParseBurrow(strings.NewReader(smallBurrow), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every neighbor of a state reachable from s has it as a predecessor.
+	reachable(b, s, func(s State) {
+		for _, n := range b.Neighbors(s) {
+			found := false
+			for _, p := range b.Predecessors(n.State) {
+				found = found || p == Neighbor{n.Step, s}
+			}
+			if !found {
+				t.Errorf("Predecessors(%v) does not contain %v, %v", n.State, s, n.Step)
+			}
+		}
+	})
+	// Every predecessor of a state reachable backward from the end has it
+	// as a neighbor.
+	visited := map[State]bool{b.End(): true}
+	for states := []State{b.End()}; len(states) > 0; {
+		s := states[len(states)-1]
+		states = states[:len(states)-1]
+		for _, p := range b.Predecessors(s) {
+			found := false
+			for _, n := range b.Neighbors(p.State) {
+				found = found || n == Neighbor{p.Step, s}
+			}
+			if !found {
+				t.Errorf("Predecessors(%v) contains %v, %v, which does not have it as a neighbor", s, p.State, p.Step)
+			}
+			if !visited[p.State] {
+				visited[p.State] = true
+				states = append(states, p.State)
+			}
+		}
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	b, s, err := ParseBurrow(strings.NewReader(smallBurrow), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := b.Heuristic(b.End()); h != 0 {
+		t.Errorf("Heuristic(End) = %d, want 0", h)
+	}
+	// Check that the heuristic is consistent on all states reachable from
+	// s. Together with Heuristic(End) == 0, this implies admissibility.
+	reachable(b, s, func(s State) {
+		h := b.Heuristic(s)
+		for _, n := range b.Moves(s) {
+			if hn := b.Heuristic(n.State); h > n.Step.Cost+hn {
+				t.Errorf("Heuristic(%v) = %d, but neighbor %v costs %d and has heuristic %d", s, h, n.State, n.Step.Cost, hn)
+			}
+		}
+	})
+}
+
+func BenchmarkOrganize(b *testing.B) {
+	inputs := []struct {
+		name   string
+		file   string
+		unfold bool
+	}{
+		{"part1", "input.txt", false},
+		{"part2", "input.txt", true},
+		{"wide", "wide.txt", false},
+	}
+	searches := []struct {
+		name string
+		opts Options
+	}{
+		{"Dijkstra", Options{}},
+		{"AStar", Options{AStar: true}},
+		{"Bidirectional", Options{Bidirectional: true, Workers: 1}},
+		{"BidirectionalAStar", Options{AStar: true, Bidirectional: true, Workers: 1}},
+		{"BidirectionalParallel", Options{AStar: true, Bidirectional: true}},
+	}
+	for _, in := range inputs {
+		burrow, s := parseFile(b, in.file, in.unfold)
+		for _, search := range searches {
+			b.Run(in.name+"/"+search.name, func(b *testing.B) {
+				var r Result
+				for i := 0; i < b.N; i++ {
+					var err error
+					if r, err = burrow.Organize(s, search.opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(r.Expanded), "states/op")
+			})
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const replHelp = `Commands:
+  move SRC DST   move the amphipod at position SRC to DST (short: m)
+  undo           undo the last move (short: u)
+  redo           redo the last undone move (short: r)
+  hints          list all legal moves (short: h)
+  solve          find the cheapest way to organize the burrow from here
+  positions      print the numbering of positions
+  show           print the burrow
+  help           print this help
+  quit           end the session (short: q)
+`
+
+// REPL runs an interactive session, in which the user can organize the burrow
+// by hand, starting from s. Commands are read from r and output is written to
+// w. opts are used to solve the burrow on request.
+func (b *Burrow) REPL(s State, opts Options, r io.Reader, w io.Writer) error {
+	type frame struct {
+		state  State
+		step   Step
+		energy int
+	}
+	history := []frame{{state: s}}
+	var undone []frame
+	cur := func() frame {
+		return history[len(history)-1]
+	}
+	show := func() {
+		fmt.Fprint(w, b.Diagram(cur().state))
+		fmt.Fprintf(w, "Energy: %d\n", cur().energy)
+	}
+
+	show()
+	sc := bufio.NewScanner(r)
+	for fmt.Fprint(w, "> "); sc.Scan(); fmt.Fprint(w, "> ") {
+		f := strings.Fields(sc.Text())
+		if len(f) == 0 {
+			continue
+		}
+		switch f[0] {
+		case "move", "m":
+			if len(f) != 3 {
+				fmt.Fprintln(w, "usage: move SRC DST")
+				continue
+			}
+			src, err1 := strconv.Atoi(f[1])
+			dst, err2 := strconv.Atoi(f[2])
+			if err1 != nil || err2 != nil || src < 0 || src >= b.Cells() || dst < 0 || dst >= b.Cells() {
+				fmt.Fprintf(w, "positions must be between 0 and %d\n", b.Cells()-1)
+				continue
+			}
+			c, next, err := b.Move(cur().state, src, dst)
+			if err != nil {
+				fmt.Fprintln(w, err)
+				continue
+			}
+			history = append(history, frame{next, Step{src, dst, c}, cur().energy + c})
+			undone = undone[:0]
+			show()
+		case "undo", "u":
+			if len(history) == 1 {
+				fmt.Fprintln(w, "nothing to undo")
+				continue
+			}
+			undone = append(undone, cur())
+			history = history[:len(history)-1]
+			show()
+		case "redo", "r":
+			if len(undone) == 0 {
+				fmt.Fprintln(w, "nothing to redo")
+				continue
+			}
+			history = append(history, undone[len(undone)-1])
+			undone = undone[:len(undone)-1]
+			show()
+		case "hints", "h":
+			ns := b.Moves(cur().state)
+			if len(ns) == 0 {
+				fmt.Fprintln(w, "no legal moves")
+			}
+			for _, n := range ns {
+				fmt.Fprintf(w, "%v %v\n", cur().state[n.Step.Src], n.Step)
+			}
+		case "solve":
+			res, err := b.Organize(cur().state, opts)
+			if err != nil {
+				fmt.Fprintln(w, err)
+				continue
+			}
+			for _, st := range res.Steps {
+				fmt.Fprintln(w, st)
+			}
+			fmt.Fprintf(w, "Organizing from here needs %d energy, %d in total\n", res.Energy, cur().energy+res.Energy)
+		case "positions":
+			fmt.Fprint(w, b.Positions())
+		case "show":
+			show()
+		case "help":
+			fmt.Fprint(w, replHelp)
+		case "quit", "q":
+			return nil
+		default:
+			fmt.Fprintf(w, "unknown command %q, try help\n", f[0])
+		}
+	}
+	return sc.Err()
+}
+
+// Positions returns a description of the numbering of positions in b.
+func (b *Burrow) Positions() string {
+	w := new(strings.Builder)
+	w.WriteString("hallway:")
+	for i := range b.columns {
+		fmt.Fprintf(w, " %d", i)
+	}
+	w.WriteString("\n")
+	for r := 0; r < b.Rooms; r++ {
+		fmt.Fprintf(w, "room %v:", A+Cell(r))
+		for _, i := range b.Homes(A + Cell(r)) {
+			fmt.Fprintf(w, " %d", i)
+		}
+		w.WriteString("\n")
+	}
+	return w.String()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	b, s := parseFile(t, "example.txt", false)
+	script := `m 9 2
+m 8 9
+m 8 1
+u
+u
+r
+h
+solve
+q
+`
+	out := new(strings.Builder)
+	if err := b.REPL(s, Options{AStar: true}, strings.NewReader(script), out); err != nil {
+		t.Fatalf("REPL(…) = %v, want <nil>", err)
+	}
+	for _, want := range []string{
+		"Energy: 40\n",
+		"Energy: 440\n",
+		"source cell is unoccupied\n",
+		"B 7 → 0 (30 energy)\n",
+		"Organizing from here needs 12481 energy, 12521 in total\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("REPL output does not contain %q:\n%s", want, out)
+		}
+	}
+}
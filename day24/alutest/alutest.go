@@ -0,0 +1,205 @@
+// Package alutest implements differential testing of ALU implementations.
+//
+// Every implementation is compared against a simple reference interpreter on
+// the same set of serials. As all implementations have to agree with the
+// reference, they also agree with each other.
+package alutest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Regs are the registers w, x, y and z of the ALU.
+type Regs [4]int
+
+// Func evaluates a program on an input.
+type Func func(in []int) Regs
+
+// Impl is an ALU implementation under test.
+type Impl struct {
+	Name string
+	// Eval returns a Func evaluating the first n instructions of the
+	// program. If Prefixes is false, it is only called with the length of
+	// the program.
+	Eval     func(n int) Func
+	Prefixes bool
+	// OnlyZ is set, if the implementation only computes z.
+	OnlyZ bool
+}
+
+// Read reads an ALU program, returning its instructions. Comments, blank
+// lines and block labels are skipped.
+func Read(r io.Reader) ([]string, error) {
+	var prog []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := s.Text()
+		if i := strings.IndexByte(l, '#'); i >= 0 {
+			l = l[:i]
+		}
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasSuffix(l, ":") {
+			continue
+		}
+		prog = append(prog, l)
+	}
+	return prog, s.Err()
+}
+
+// Ref runs prog on in, using the reference interpreter. It returns an error,
+// if the program would crash the ALU.
+func Ref(prog []string, in []int) (Regs, error) {
+	var r Regs
+	reg := func(s string) (*int, bool) {
+		if len(s) != 1 || s[0] < 'w' || s[0] > 'z' {
+			return nil, false
+		}
+		return &r[s[0]-'w'], true
+	}
+	for n, l := range prog {
+		f := strings.Fields(l)
+		if len(f) < 2 {
+			return r, fmt.Errorf("instruction %d: invalid instruction %q", n, l)
+		}
+		a, ok := reg(f[1])
+		if !ok {
+			return r, fmt.Errorf("instruction %d: invalid register %q", n, f[1])
+		}
+		if f[0] == "inp" {
+			if len(in) == 0 {
+				return r, fmt.Errorf("instruction %d: input exhausted", n)
+			}
+			*a, in = in[0], in[1:]
+			continue
+		}
+		if len(f) != 3 {
+			return r, fmt.Errorf("instruction %d: invalid instruction %q", n, l)
+		}
+		var b int
+		if p, ok := reg(f[2]); ok {
+			b = *p
+		} else if v, err := strconv.Atoi(f[2]); err == nil {
+			b = v
+		} else {
+			return r, fmt.Errorf("instruction %d: invalid argument %q", n, f[2])
+		}
+		switch f[0] {
+		case "add":
+			*a += b
+		case "mul":
+			*a *= b
+		case "div":
+			if b == 0 {
+				return r, fmt.Errorf("instruction %d: division by zero", n)
+			}
+			*a /= b
+		case "mod":
+			if *a < 0 || b <= 0 {
+				return r, fmt.Errorf("instruction %d: invalid mod %d %% %d", n, *a, b)
+			}
+			*a %= b
+		case "eql":
+			if *a == b {
+				*a = 1
+			} else {
+				*a = 0
+			}
+		default:
+			return r, fmt.Errorf("instruction %d: unknown instruction %q", n, f[0])
+		}
+	}
+	return r, nil
+}
+
+// Serials returns a set of edge-case serials of length n, followed by nRandom
+// random serials. The random serials are deterministic for a given seed.
+func Serials(n, nRandom int, seed int64) [][]int {
+	var out [][]int
+	for d := 1; d <= 9; d++ {
+		s := make([]int, n)
+		for i := range s {
+			s[i] = d
+		}
+		out = append(out, s)
+	}
+	up, down, alt := make([]int, n), make([]int, n), make([]int, n)
+	for i := 0; i < n; i++ {
+		up[i] = i%9 + 1
+		down[i] = 9 - i%9
+		alt[i] = 1 + 8*(i%2)
+	}
+	out = append(out, up, down, alt)
+	rnd := rand.New(rand.NewSource(seed))
+	for ; nRandom > 0; nRandom-- {
+		s := make([]int, n)
+		for i := range s {
+			s[i] = rnd.Intn(9) + 1
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// Check compares all impls against the reference interpreter, running prog on
+// all serials. Serials which crash the ALU are skipped.
+//
+// If an implementation diverges and supports prefixes, the shortest prefix of
+// prog on which it diverges is reported.
+func Check(t *testing.T, prog []string, serials [][]int, impls ...Impl) {
+	t.Helper()
+	for _, impl := range impls {
+		eval := impl.Eval(len(prog))
+		for _, in := range serials {
+			want, err := Ref(prog, in)
+			if err != nil {
+				continue
+			}
+			got := eval(in)
+			if impl.agrees(got, want) {
+				continue
+			}
+			if !impl.Prefixes {
+				t.Errorf("%s(%s) = %v, want %v", impl.Name, serial(in), got, want)
+				break
+			}
+			n, got, want := impl.firstDivergence(prog, in)
+			t.Errorf("%s diverges on %s after %d instructions, at instruction %d (%q): got %v, want %v", impl.Name, serial(in), n, n-1, prog[n-1], got, want)
+			break
+		}
+	}
+}
+
+func (impl Impl) agrees(got, want Regs) bool {
+	if impl.OnlyZ {
+		return got[3] == want[3]
+	}
+	return got == want
+}
+
+// firstDivergence returns the length of the shortest prefix of prog on which
+// impl diverges from the reference, when run on in.
+func (impl Impl) firstDivergence(prog []string, in []int) (n int, got, want Regs) {
+	for n = 1; n < len(prog); n++ {
+		want, _ = Ref(prog[:n], in)
+		got = impl.Eval(n)(in)
+		if !impl.agrees(got, want) {
+			return n, got, want
+		}
+	}
+	want, _ = Ref(prog, in)
+	return len(prog), impl.Eval(len(prog))(in), want
+}
+
+func serial(in []int) string {
+	b := make([]byte, len(in))
+	for i, v := range in {
+		b[i] = byte(v) + '0'
+	}
+	return string(b)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// The text format of ALU programs has one instruction per line. Everything
+// after a '#' is a comment and blank lines are ignored. A line of the form
+// "name:" labels the block started by the next instruction, which must be an
+// inp instruction:
+//
+//	# Push input[0]+4.
+//	push0:
+//		inp w
+//		mul x 0   # x = 0
+//		...
+
+// program is an ALU program, together with the labels of its blocks.
+type program struct {
+	insts []instruction
+	// labels maps the index of an inp instruction to the label of the
+	// block it starts.
+	labels map[int]string
+}
+
+// syntaxError is an error in an ALU program. Line and column are 1-based.
+type syntaxError struct {
+	line int
+	col  int
+	msg  string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.line, e.col, e.msg)
+}
+
+// field is a whitespace-separated field of a line, with its 1-based column.
+type field struct {
+	s   string
+	col int
+}
+
+func fields(l string) []field {
+	var out []field
+	start := -1
+	for i, r := range l {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				out = append(out, field{l[start:i], start + 1})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		out = append(out, field{l[start:], start + 1})
+	}
+	return out
+}
+
+// parse parses an ALU program.
+func parse(r io.Reader) (*program, error) {
+	p := &program{labels: make(map[int]string)}
+	var (
+		line  int
+		label *field
+		seen  = make(map[string]int)
+	)
+	errorf := func(col int, format string, args ...interface{}) error {
+		return &syntaxError{line, col, fmt.Sprintf(format, args...)}
+	}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line++
+		l := s.Text()
+		if i := strings.IndexByte(l, '#'); i >= 0 {
+			l = l[:i]
+		}
+		f := fields(l)
+		if len(f) == 0 {
+			continue
+		}
+		if strings.HasSuffix(f[0].s, ":") {
+			if len(f) > 1 {
+				return nil, errorf(f[1].col, "unexpected %q after label", f[1].s)
+			}
+			name := strings.TrimSuffix(f[0].s, ":")
+			if name == "" {
+				return nil, errorf(f[0].col, "empty label")
+			}
+			if label != nil {
+				return nil, errorf(f[0].col, "label %q follows label %q", name, label.s)
+			}
+			if l, ok := seen[name]; ok {
+				return nil, errorf(f[0].col, "label %q already defined on line %d", name, l)
+			}
+			seen[name] = line
+			label = &field{name, f[0].col}
+			continue
+		}
+
+		var (
+			i     instruction
+			nArgs = 2
+		)
+		switch f[0].s {
+		case "inp":
+			i.op, nArgs = opInp, 1
+		case "add":
+			i.op = opAdd
+		case "mul":
+			i.op = opMul
+		case "div":
+			i.op = opDiv
+		case "mod":
+			i.op = opMod
+		case "eql":
+			i.op = opEql
+		default:
+			return nil, errorf(f[0].col, "unknown instruction %q", f[0].s)
+		}
+		if len(f) < nArgs+1 {
+			last := f[len(f)-1]
+			return nil, errorf(last.col+len(last.s), "%s needs %d arguments, got %d", f[0].s, nArgs, len(f)-1)
+		}
+		if len(f) > nArgs+1 {
+			return nil, errorf(f[nArgs+1].col, "unexpected %q after %s", f[nArgs+1].s, f[0].s)
+		}
+		var err error
+		if i.arg1, err = parseVar(f[1].s); err != nil {
+			return nil, errorf(f[1].col, "%v", err)
+		}
+		if nArgs > 1 {
+			if i.arg2, err = parseArg(f[2].s); err != nil {
+				return nil, errorf(f[2].col, "invalid argument %q", f[2].s)
+			}
+		}
+		if label != nil {
+			if i.op != opInp {
+				return nil, errorf(f[0].col, "label %q must be followed by inp, not %s", label.s, f[0].s)
+			}
+			p.labels[len(p.insts)] = label.s
+			label = nil
+		}
+		p.insts = append(p.insts, i)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if label != nil {
+		return nil, &syntaxError{line, label.col, fmt.Sprintf("label %q at end of program", label.s)}
+	}
+	return p, nil
+}
+
+// read reads the instructions of an ALU program.
+func read(r io.Reader) ([]instruction, error) {
+	p, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.insts, nil
+}
+
+// format writes p in canonical form. Every block is preceded by a blank line
+// and its label. Blocks without a label are labelled by their index. The
+// output can be read by parse.
+func (p *program) format(w io.Writer) error {
+	used := make(map[string]bool)
+	for _, l := range p.labels {
+		used[l] = true
+	}
+	bw := bufio.NewWriter(w)
+	var block int
+	for i, inst := range p.insts {
+		if inst.op == opInp {
+			if i > 0 {
+				bw.WriteByte('\n')
+			}
+			label, ok := p.labels[i]
+			if !ok {
+				label = fmt.Sprintf("block%d", block)
+				for n := 1; used[label]; n++ {
+					label = fmt.Sprintf("block%d.%d", block, n)
+				}
+			}
+			fmt.Fprintf(bw, "%s:\n", label)
+			block++
+		}
+		fmt.Fprintf(bw, "\t%v\n", inst)
+	}
+	return bw.Flush()
+}
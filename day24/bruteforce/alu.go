@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type op int
+
+const (
+	opInp op = iota
+	opAdd
+	opMul
+	opDiv
+	opMod
+	opEql
+)
+
+type instruction struct {
+	op  op
+	a   int
+	b   int
+	imm bool
+}
+
+func read(r io.Reader) ([]instruction, error) {
+	var out []instruction
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := s.Text()
+		if i := strings.IndexByte(l, '#'); i >= 0 {
+			l = l[:i]
+		}
+		f := strings.Fields(l)
+		// Skip blank lines and block labels.
+		if len(f) == 0 || (len(f) == 1 && strings.HasSuffix(f[0], ":")) {
+			continue
+		}
+		var (
+			i     instruction
+			nArgs = 2
+		)
+		switch f[0] {
+		case "inp":
+			i.op, nArgs = opInp, 1
+		case "add":
+			i.op = opAdd
+		case "mul":
+			i.op = opMul
+		case "div":
+			i.op = opDiv
+		case "mod":
+			i.op = opMod
+		case "eql":
+			i.op = opEql
+		default:
+			return nil, fmt.Errorf("unknown instruction %q", f[0])
+		}
+		if len(f) != nArgs+1 {
+			return nil, fmt.Errorf("%s takes %d arguments, got %d", f[0], nArgs, len(f)-1)
+		}
+		var err error
+		if i.a, err = parseReg(f[1]); err != nil {
+			return nil, err
+		}
+		if nArgs == 2 {
+			if i.b, err = parseReg(f[2]); err != nil {
+				i.imm = true
+				if i.b, err = strconv.Atoi(f[2]); err != nil {
+					return nil, fmt.Errorf("invalid argument %q", f[2])
+				}
+			}
+		}
+		out = append(out, i)
+	}
+	return out, s.Err()
+}
+
+func parseReg(s string) (int, error) {
+	switch s {
+	case "w":
+		return 0, nil
+	case "x":
+		return 1, nil
+	case "y":
+		return 2, nil
+	case "z":
+		return 3, nil
+	default:
+		return 0, fmt.Errorf("unknown register %q", s)
+	}
+}
+
+// Regs are the registers w, x, y and z of the ALU.
+type Regs [4]int
+
+// Program is an ALU program, compiled into a sequence of closures. The
+// closures are split into segments, each starting with an inp instruction, so
+// that a program can be run incrementally, one input at a time.
+type Program struct {
+	segments [][]func(*Regs, []int)
+	// shrink[i] is the product of all divisors of z in segments[i:].
+	shrink []int
+	inputs int
+}
+
+// Compile compiles prog into a Program.
+func Compile(prog []instruction) *Program {
+	p := &Program{segments: make([][]func(*Regs, []int), 1)}
+	div := []int{1}
+	for _, i := range prog {
+		if i.op == opInp {
+			if p.inputs > 0 {
+				p.segments = append(p.segments, nil)
+				div = append(div, 1)
+			}
+			p.inputs++
+		}
+		n := len(p.segments) - 1
+		p.segments[n] = append(p.segments[n], compileInst(i, p.inputs-1))
+		if i.op == opDiv && i.a == 3 && i.imm && i.b > 1 {
+			div[n] = mulSat(div[n], i.b)
+		}
+	}
+	p.shrink = make([]int, len(div)+1)
+	p.shrink[len(div)] = 1
+	for i := len(div) - 1; i >= 0; i-- {
+		p.shrink[i] = mulSat(p.shrink[i+1], div[i])
+	}
+	return p
+}
+
+// mulSat returns a*b for positive a and b, saturating at math.MaxInt.
+func mulSat(a, b int) int {
+	if a > math.MaxInt/b {
+		return math.MaxInt
+	}
+	return a * b
+}
+
+func compileInst(i instruction, inp int) func(*Regs, []int) {
+	a, b := i.a, i.b
+	if i.op == opInp {
+		return func(r *Regs, in []int) { r[a] = in[inp] }
+	}
+	if i.imm {
+		switch i.op {
+		case opAdd:
+			return func(r *Regs, _ []int) { r[a] += b }
+		case opMul:
+			return func(r *Regs, _ []int) { r[a] *= b }
+		case opDiv:
+			return func(r *Regs, _ []int) { r[a] /= b }
+		case opMod:
+			return func(r *Regs, _ []int) { r[a] %= b }
+		case opEql:
+			return func(r *Regs, _ []int) { r[a] = b2i(r[a] == b) }
+		}
+	} else {
+		switch i.op {
+		case opAdd:
+			return func(r *Regs, _ []int) { r[a] += r[b] }
+		case opMul:
+			return func(r *Regs, _ []int) { r[a] *= r[b] }
+		case opDiv:
+			return func(r *Regs, _ []int) { r[a] /= r[b] }
+		case opMod:
+			return func(r *Regs, _ []int) { r[a] %= r[b] }
+		case opEql:
+			return func(r *Regs, _ []int) { r[a] = b2i(r[a] == r[b]) }
+		}
+	}
+	panic(fmt.Sprintf("invalid op code %d", int(i.op)))
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Inputs returns the number of inputs read by p.
+func (p *Program) Inputs() int {
+	return p.inputs
+}
+
+// Run runs p on the given input and returns the final registers.
+func (p *Program) Run(in []int) Regs {
+	var r Regs
+	for i := range p.segments {
+		p.RunSegment(&r, in, i)
+	}
+	return r
+}
+
+// RunSegment runs the segment of p reading in[i], up to the next inp
+// instruction.
+func (p *Program) RunSegment(r *Regs, in []int, i int) {
+	for _, s := range p.segments[i] {
+		s(r, in)
+	}
+}
+
+// Unreachable reports whether z can not be reduced to 0 anymore, if r are the
+// registers after running the first n segments.
+//
+// This assumes that z is only ever made smaller by dividing it by a constant,
+// as is the case for the puzzle input: Every segment there computes
+// z = z/d*(25*x+1) + (w+c)*x, for some x ∈ {0,1}. So if z is at least as
+// large as the product of all remaining divisors, it stays positive.
+func (p *Program) Unreachable(r Regs, n int) bool {
+	return r[3] >= p.shrink[n]
+}
+
+// Pred is a predicate on the final registers of a program run.
+type Pred func(Regs) bool
+
+var predRegexp = regexp.MustCompile(`^\s*([wxyz])\s*(==|!=|<=|>=|<|>)\s*(-?[0-9]+)\s*$`)
+
+// ParsePred parses a predicate of the form "z==0", comparing a register
+// against a constant.
+func ParsePred(s string) (Pred, error) {
+	m := predRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid predicate %q", s)
+	}
+	reg, err := parseReg(m[1])
+	if err != nil {
+		return nil, err
+	}
+	c, err := strconv.Atoi(m[3])
+	if err != nil {
+		return nil, err
+	}
+	switch m[2] {
+	case "==":
+		return func(r Regs) bool { return r[reg] == c }, nil
+	case "!=":
+		return func(r Regs) bool { return r[reg] != c }, nil
+	case "<=":
+		return func(r Regs) bool { return r[reg] <= c }, nil
+	case ">=":
+		return func(r Regs) bool { return r[reg] >= c }, nil
+	case "<":
+		return func(r Regs) bool { return r[reg] < c }, nil
+	case ">":
+		return func(r Regs) bool { return r[reg] > c }, nil
+	default:
+		panic("unreachable")
+	}
+}
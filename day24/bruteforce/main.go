@@ -2,57 +2,108 @@ package main
 
 import (
 	"bufio"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"math"
+	"math/rand"
 	"os"
 	"runtime"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
 func main() {
 	log.SetFlags(0)
+	var (
+		progFile  = flag.String("prog", "../input.txt", "file containing the ALU program to brute force")
+		nInput    = flag.Int("inputs", 14, "number of input digits read by the program")
+		blockSize = flag.Int("block", 6, "number of digits enumerated by a worker per block")
+		predStr   = flag.String("pred", "z==0", "predicate on the final registers a serial must satisfy")
+		check     = flag.Int("check", 0, "compare the program against the generated eval on this many random inputs and exit")
+		httpAddr  = flag.String("http", "", "if set, serve progress as JSON under /progress and Prometheus metrics under /metrics on this address")
+		prune     = flag.Bool("prune", false, "skip inputs for which z can't reach 0 anymore (only valid for -pred z==0 and programs shaped like the puzzle input)")
+	)
+	flag.Parse()
+
+	f, err := os.Open(*progFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	insts, err := read(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	prog := Compile(insts)
+	if prog.Inputs() != *nInput {
+		log.Fatalf("program reads %d inputs, but -inputs is %d", prog.Inputs(), *nInput)
+	}
+	if prog.Inputs() == 0 {
+		log.Fatal("program reads no input")
+	}
+	if *blockSize < 0 || *blockSize > *nInput {
+		log.Fatalf("-block must be between 0 and %d", *nInput)
+	}
+	pred, err := ParsePred(*predStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *prune && !isZeroPred(*predStr) {
+		log.Fatal("-prune requires -pred z==0")
+	}
+	if *check > 0 {
+		if err := CheckGenerated(prog, *check); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("program agrees with generated eval on %d inputs", *check)
+		return
+	}
+
 	out, err := os.Create("serials.txt")
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	nWorkers := runtime.NumCPU()
+	progress := NewProgress(math.Pow(9, float64(*nInput-*blockSize)), nWorkers)
+	if *httpAddr != "" {
+		go func() {
+			log.Fatal(progress.Serve(*httpAddr))
+		}()
+	}
+
 	wg := new(sync.WaitGroup)
-	blocks := make(chan Block)
+	blocks := make(chan []int)
 	serials := make(chan string)
 	done := make(chan struct{})
-	for i := 0; i < runtime.NumCPU(); i++ {
+	for i := 0; i < nWorkers; i++ {
 		wg.Add(1)
-		go Worker(wg, blocks, serials)
+		go Worker(wg, prog, pred, *prune, blocks, serials, progress.Worker(i))
 	}
 	go WriteSerials(out, serials, done)
-	var nBlocks uint64
 	go func() {
-		tick := time.Tick(10 * time.Second)
-		last := uint64(0)
+		tick := time.NewTicker(10 * time.Second)
+		defer tick.Stop()
 		for {
 			select {
-			case <-tick:
-				n := atomic.LoadUint64(&nBlocks)
-				log.Printf("%d blocks generated (%f blocks/s, %f inputs/s)", n, float64(n-last)/10, float64(n-last)/10*math.Pow(9, blockSize))
-				last = n
+			case <-tick.C:
+				log.Println(progress.Snapshot())
 			case <-done:
 				return
 			}
 		}
 	}()
 
-	var b Block
+	b := make([]int, *nInput-*blockSize)
 	for i := range b {
 		b[i] = 1
 	}
 	for {
-		blocks <- b
-		atomic.AddUint64(&nBlocks, 1)
-		if inc(b[:]) {
+		blocks <- append([]int(nil), b...)
+		if inc(b) {
 			break
 		}
 	}
@@ -60,39 +111,83 @@ func main() {
 	wg.Wait()
 	close(serials)
 	<-done
+	log.Print(progress.Snapshot().Summary())
 }
 
-const (
-	blockSize = 6
-	nInput    = 14
-)
-
-type Block [nInput - blockSize]int
-
-func Worker(wg *sync.WaitGroup, ch <-chan Block, serials chan<- string) {
+// Worker runs p on all inputs starting with a block read from ch, sending
+// every input satisfying pred to serials and recording its progress in wp.
+//
+// The program is evaluated incrementally, one digit at a time, so the state
+// after a prefix is shared between all its suffixes. If prune is set, prefixes
+// for which z can't reach 0 anymore are skipped entirely.
+func Worker(wg *sync.WaitGroup, p *Program, pred Pred, prune bool, ch <-chan []int, serials chan<- string, wp *WorkerProgress) {
 	defer wg.Done()
 
 	buf := new(strings.Builder)
 
-	in := make([]int, nInput)
-	for i := range in {
-		in[i] = 1
-	}
-	for b := range ch {
-		copy(in, b[:])
-		for {
-			if eval(in) == 0 {
+	in := make([]int, p.Inputs())
+	var search func(r Regs, i int)
+	search = func(r Regs, i int) {
+		if i == len(in) {
+			if pred(r) {
 				for _, v := range in {
 					buf.WriteByte(byte(v) + '0')
 				}
 				serials <- buf.String()
 				buf.Reset()
+				wp.Serial()
+			}
+			return
+		}
+		for d := 1; d <= 9; d++ {
+			in[i] = d
+			r := r
+			p.RunSegment(&r, in, i)
+			if prune && p.Unreachable(r, i+1) {
+				continue
 			}
-			if inc(in[nInput-blockSize:]) {
-				break
+			search(r, i+1)
+		}
+	}
+
+blocks:
+	for b := range ch {
+		copy(in, b)
+		var r Regs
+		for i := range b {
+			p.RunSegment(&r, in, i)
+			if prune && p.Unreachable(r, i+1) {
+				wp.Block()
+				continue blocks
 			}
 		}
+		search(r, len(b))
+		wp.Block()
+	}
+}
+
+// isZeroPred reports whether the predicate s is z==0.
+func isZeroPred(s string) bool {
+	m := predRegexp.FindStringSubmatch(s)
+	return m != nil && m[1] == "z" && m[2] == "==" && m[3] == "0"
+}
+
+// CheckGenerated compares p against the generated eval on n random inputs.
+func CheckGenerated(p *Program, n int) error {
+	if p.Inputs() != 14 {
+		return fmt.Errorf("generated eval reads 14 inputs, program reads %d", p.Inputs())
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	in := make([]int, 14)
+	for ; n > 0; n-- {
+		for i := range in {
+			in[i] = rnd.Intn(9) + 1
+		}
+		if got, want := p.Run(in)[3], eval(in); got != want {
+			return fmt.Errorf("input %v: program computes z=%d, generated eval computes z=%d", in, got, want)
+		}
 	}
+	return nil
 }
 
 // inc increments the block by b, returning true if a wrap-around occured.
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Merovius/aoc_2021/day24/alutest"
+)
+
+func TestEval(t *testing.T) {
+	f, err := os.Open("../input.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := alutest.Read(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := read(strings.NewReader(strings.Join(src, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alutest.Check(t, src, alutest.Serials(14, 10000, 1),
+		alutest.Impl{
+			Name: "generated eval",
+			Eval: func(int) alutest.Func {
+				return func(in []int) alutest.Regs {
+					return alutest.Regs{3: eval(in)}
+				}
+			},
+			OnlyZ: true,
+		},
+		alutest.Impl{
+			Name: "compiled program",
+			Eval: func(n int) alutest.Func {
+				p := Compile(prog[:n])
+				return func(in []int) alutest.Regs {
+					return alutest.Regs(p.Run(in))
+				}
+			},
+			Prefixes: true,
+		},
+	)
+}
+
+// search runs a Worker on the given blocks and returns the serials it finds,
+// in order.
+func search(p *Program, prune bool, blocks ...[]int) []string {
+	ch := make(chan []int, len(blocks))
+	for _, b := range blocks {
+		ch <- b
+	}
+	close(ch)
+	serials := make(chan string)
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	go Worker(wg, p, func(r Regs) bool { return r[3] == 0 }, prune, ch, serials, new(WorkerProgress))
+	go func() {
+		wg.Wait()
+		close(serials)
+	}()
+	var out []string
+	for s := range serials {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestPrune(t *testing.T) {
+	f, err := os.Open("../input.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	insts, err := read(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Compile(insts)
+	// The prefixes of the largest and smallest serial, and one which can
+	// be pruned right away.
+	blocks := [][]int{
+		{9, 1, 3, 9, 8, 2, 9, 9},
+		{4, 1, 1, 7, 1, 1, 8, 3},
+		{9, 9, 9, 9, 9, 9, 9, 9},
+	}
+	want := search(p, false, blocks...)
+	got := search(p, true, blocks...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("search with pruning found %v, without pruning %v", got, want)
+	}
+	for _, s := range []string{"91398299697996", "41171183141291"} {
+		if i := sort.SearchStrings(want, s); i == len(want) || want[i] != s {
+			t.Errorf("search did not find %s", s)
+		}
+	}
+}
+
+func TestMulSat(t *testing.T) {
+	tcs := []struct {
+		a, b int
+		want int
+	}{
+		{2, 3, 6},
+		{1, math.MaxInt, math.MaxInt},
+		{math.MaxInt, 1, math.MaxInt},
+		{math.MaxInt/2 + 1, 2, math.MaxInt},
+		{1 << 32, 1 << 32, math.MaxInt},
+		{math.MaxInt, math.MaxInt, math.MaxInt},
+	}
+	for _, tc := range tcs {
+		if got := mulSat(tc.a, tc.b); got != tc.want {
+			t.Errorf("mulSat(%d, %d) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestShrink(t *testing.T) {
+	// Only divisions of z by constants larger than 1 count.
+	src := strings.Repeat("inp w\ndiv z 100000\ndiv z 1\ndiv x 7\ndiv z w\n", 14)
+	insts, err := read(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Compile(insts)
+	want := make([]int, 15)
+	want[14] = 1
+	for i := 13; i >= 0; i-- {
+		want[i] = math.MaxInt
+		if 14-i <= 3 {
+			want[i] = want[i+1] * 100000
+		}
+	}
+	if !reflect.DeepEqual(p.shrink, want) {
+		t.Errorf("Compile(%q).shrink = %v, want %v", src, p.shrink, want)
+	}
+	if p.Unreachable(Regs{3: math.MaxInt - 1}, 0) {
+		t.Errorf("Unreachable(z=MaxInt-1, 0) = true, want false")
+	}
+	if !p.Unreachable(Regs{3: 100000}, 13) {
+		t.Errorf("Unreachable(z=100000, 13) = false, want true")
+	}
+}
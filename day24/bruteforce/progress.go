@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Progress tracks the progress of a brute force run.
+type Progress struct {
+	start   time.Time
+	total   float64
+	workers []*WorkerProgress
+}
+
+// WorkerProgress tracks the progress of a single worker. Its counters are
+// updated atomically.
+type WorkerProgress struct {
+	blocks  uint64
+	serials uint64
+}
+
+// NewProgress returns a Progress for a run searching total blocks, using
+// nWorkers workers.
+func NewProgress(total float64, nWorkers int) *Progress {
+	p := &Progress{start: time.Now(), total: total}
+	for i := 0; i < nWorkers; i++ {
+		p.workers = append(p.workers, new(WorkerProgress))
+	}
+	return p
+}
+
+// Worker returns the WorkerProgress of the i'th worker.
+func (p *Progress) Worker(i int) *WorkerProgress {
+	return p.workers[i]
+}
+
+// Block records that a block has been searched.
+func (w *WorkerProgress) Block() {
+	atomic.AddUint64(&w.blocks, 1)
+}
+
+// Serial records that a serial has been found.
+func (w *WorkerProgress) Serial() {
+	atomic.AddUint64(&w.serials, 1)
+}
+
+// Snapshot is the state of a Progress at a point in time.
+type Snapshot struct {
+	Elapsed float64          `json:"elapsed_seconds"`
+	Blocks  uint64           `json:"blocks_done"`
+	Total   float64          `json:"blocks_total"`
+	Percent float64          `json:"percent"`
+	ETA     float64          `json:"eta_seconds"`
+	Serials uint64           `json:"serials"`
+	Rate    float64          `json:"blocks_per_second"`
+	Workers []WorkerSnapshot `json:"workers"`
+}
+
+// WorkerSnapshot is the state of a WorkerProgress at a point in time.
+type WorkerSnapshot struct {
+	Blocks  uint64  `json:"blocks_done"`
+	Serials uint64  `json:"serials"`
+	Rate    float64 `json:"blocks_per_second"`
+}
+
+// Snapshot returns the current state of p. Rates are averaged over the entire
+// run so far.
+func (p *Progress) Snapshot() Snapshot {
+	return p.snapshot(time.Now())
+}
+
+func (p *Progress) snapshot(now time.Time) Snapshot {
+	s := Snapshot{
+		Elapsed: now.Sub(p.start).Seconds(),
+		Total:   p.total,
+	}
+	for _, w := range p.workers {
+		ws := WorkerSnapshot{
+			Blocks:  atomic.LoadUint64(&w.blocks),
+			Serials: atomic.LoadUint64(&w.serials),
+		}
+		if s.Elapsed > 0 {
+			ws.Rate = float64(ws.Blocks) / s.Elapsed
+		}
+		s.Blocks += ws.Blocks
+		s.Serials += ws.Serials
+		s.Rate += ws.Rate
+		s.Workers = append(s.Workers, ws)
+	}
+	s.Percent = 100 * float64(s.Blocks) / s.Total
+	if s.Rate > 0 {
+		s.ETA = (s.Total - float64(s.Blocks)) / s.Rate
+	} else {
+		s.ETA = math.Inf(1)
+	}
+	return s
+}
+
+func (s Snapshot) String() string {
+	eta := "?"
+	if s.ETA < float64(math.MaxInt64/time.Second) {
+		eta = (time.Duration(s.ETA) * time.Second).String()
+	}
+	return fmt.Sprintf("%d/%.0f blocks (%.2f%%), %.1f blocks/s, %d serials, ETA %s", s.Blocks, s.Total, s.Percent, s.Rate, s.Serials, eta)
+}
+
+// Summary returns a multi-line summary of s, meant to be printed at the end
+// of a run.
+func (s Snapshot) Summary() string {
+	w := new(strings.Builder)
+	fmt.Fprintf(w, "searched %d blocks in %v (%.1f blocks/s), found %d serials\n", s.Blocks, time.Duration(s.Elapsed*float64(time.Second)).Round(time.Millisecond), s.Rate, s.Serials)
+	for i, ws := range s.Workers {
+		fmt.Fprintf(w, "  worker %d: %d blocks (%.1f blocks/s), %d serials\n", i, ws.Blocks, ws.Rate, ws.Serials)
+	}
+	return w.String()
+}
+
+// WritePrometheus writes s in the Prometheus text exposition format.
+func (s Snapshot) WritePrometheus(w io.Writer) error {
+	b := new(strings.Builder)
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(b, "# HELP bruteforce_%s %s\n", name, help)
+		fmt.Fprintf(b, "# TYPE bruteforce_%s %s\n", name, typ)
+	}
+	metric("elapsed_seconds", "gauge", "Time since the run started.")
+	fmt.Fprintf(b, "bruteforce_elapsed_seconds %v\n", s.Elapsed)
+	metric("blocks_done", "counter", "Number of blocks searched.")
+	fmt.Fprintf(b, "bruteforce_blocks_done %d\n", s.Blocks)
+	metric("blocks_total", "gauge", "Number of blocks to search.")
+	fmt.Fprintf(b, "bruteforce_blocks_total %v\n", s.Total)
+	metric("eta_seconds", "gauge", "Estimated time until the run is finished.")
+	fmt.Fprintf(b, "bruteforce_eta_seconds %v\n", s.ETA)
+	metric("serials_found", "counter", "Number of serials found.")
+	fmt.Fprintf(b, "bruteforce_serials_found %d\n", s.Serials)
+	metric("worker_blocks_done", "counter", "Number of blocks searched by a worker.")
+	for i, ws := range s.Workers {
+		fmt.Fprintf(b, "bruteforce_worker_blocks_done{worker=\"%d\"} %d\n", i, ws.Blocks)
+	}
+	metric("worker_serials_found", "counter", "Number of serials found by a worker.")
+	for i, ws := range s.Workers {
+		fmt.Fprintf(b, "bruteforce_worker_serials_found{worker=\"%d\"} %d\n", i, ws.Serials)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Serve serves the progress on addr, using Handler.
+func (p *Progress) Serve(addr string) error {
+	return http.ListenAndServe(addr, p.Handler())
+}
+
+// Handler returns a handler serving the progress as JSON under /progress and
+// as Prometheus metrics under /metrics. An unknown ETA is served as -1 in JSON.
+func (p *Progress) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/progress", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		s := p.Snapshot()
+		if math.IsInf(s.ETA, 1) {
+			// JSON can't represent infinity.
+			s.ETA = -1
+		}
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			log.Println(err)
+		}
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := p.Snapshot().WritePrometheus(w); err != nil {
+			log.Println(err)
+		}
+	})
+	return mux
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newProgress returns a Progress with the given counters per worker, started
+// at start.
+func newProgress(start time.Time, total float64, blocks, serials []uint64) *Progress {
+	p := NewProgress(total, len(blocks))
+	p.start = start
+	for i, w := range p.workers {
+		w.blocks, w.serials = blocks[i], serials[i]
+	}
+	return p
+}
+
+func TestSnapshot(t *testing.T) {
+	start := time.Date(2021, 12, 24, 0, 0, 0, 0, time.UTC)
+	tcs := []struct {
+		name    string
+		elapsed time.Duration
+		total   float64
+		blocks  []uint64
+		serials []uint64
+		want    Snapshot
+	}{
+		{
+			name:    "running",
+			elapsed: 10 * time.Second,
+			total:   100,
+			blocks:  []uint64{10, 20},
+			serials: []uint64{1, 2},
+			want: Snapshot{
+				Elapsed: 10, Blocks: 30, Total: 100, Percent: 30, ETA: 70.0 / 3, Serials: 3, Rate: 3,
+				Workers: []WorkerSnapshot{{10, 1, 1}, {20, 2, 2}},
+			},
+		},
+		{
+			name:    "done",
+			elapsed: 4 * time.Second,
+			total:   8,
+			blocks:  []uint64{8},
+			serials: []uint64{0},
+			want: Snapshot{
+				Elapsed: 4, Blocks: 8, Total: 8, Percent: 100, ETA: 0, Rate: 2,
+				Workers: []WorkerSnapshot{{8, 0, 2}},
+			},
+		},
+		{
+			name:    "no blocks",
+			elapsed: time.Second,
+			total:   10,
+			blocks:  []uint64{0, 0},
+			serials: []uint64{0, 0},
+			want: Snapshot{
+				Elapsed: 1, Total: 10, ETA: math.Inf(1),
+				Workers: []WorkerSnapshot{{}, {}},
+			},
+		},
+		{
+			name:    "just started",
+			elapsed: 0,
+			total:   10,
+			blocks:  []uint64{1},
+			serials: []uint64{0},
+			want: Snapshot{
+				Blocks: 1, Total: 10, Percent: 10, ETA: math.Inf(1),
+				Workers: []WorkerSnapshot{{1, 0, 0}},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		p := newProgress(start, tc.total, tc.blocks, tc.serials)
+		if got := p.snapshot(start.Add(tc.elapsed)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: snapshot() = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	s := Snapshot{
+		Elapsed: 1.5, Blocks: 3, Total: 81, Percent: 3.7, ETA: math.Inf(1), Serials: 4, Rate: 2,
+		Workers: []WorkerSnapshot{{1, 4, 0.5}, {2, 0, 1.5}},
+	}
+	want := `# HELP bruteforce_elapsed_seconds Time since the run started.
+# TYPE bruteforce_elapsed_seconds gauge
+bruteforce_elapsed_seconds 1.5
+# HELP bruteforce_blocks_done Number of blocks searched.
+# TYPE bruteforce_blocks_done counter
+bruteforce_blocks_done 3
+# HELP bruteforce_blocks_total Number of blocks to search.
+# TYPE bruteforce_blocks_total gauge
+bruteforce_blocks_total 81
+# HELP bruteforce_eta_seconds Estimated time until the run is finished.
+# TYPE bruteforce_eta_seconds gauge
+bruteforce_eta_seconds +Inf
+# HELP bruteforce_serials_found Number of serials found.
+# TYPE bruteforce_serials_found counter
+bruteforce_serials_found 4
+# HELP bruteforce_worker_blocks_done Number of blocks searched by a worker.
+# TYPE bruteforce_worker_blocks_done counter
+bruteforce_worker_blocks_done{worker="0"} 1
+bruteforce_worker_blocks_done{worker="1"} 2
+# HELP bruteforce_worker_serials_found Number of serials found by a worker.
+# TYPE bruteforce_worker_serials_found counter
+bruteforce_worker_serials_found{worker="0"} 4
+bruteforce_worker_serials_found{worker="1"} 0
+`
+	b := new(strings.Builder)
+	if err := s.WritePrometheus(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WritePrometheus() wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	// Without any blocks searched, the ETA is unknown.
+	p := newProgress(time.Now(), 10, []uint64{0, 0}, []uint64{0, 0})
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/progress", nil))
+	if ct := w.Result().Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET /progress has Content-Type %q, want application/json", ct)
+	}
+	var s Snapshot
+	if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ETA != -1 || s.Total != 10 || len(s.Workers) != 2 {
+		t.Errorf("GET /progress = %+v, want ETA -1, 10 blocks total and 2 workers", s)
+	}
+
+	w = httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if body := w.Body.String(); !strings.Contains(body, "bruteforce_eta_seconds +Inf\n") {
+		t.Errorf("GET /metrics = %q, want an infinite ETA", body)
+	}
+}
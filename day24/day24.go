@@ -4,30 +4,36 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"math"
 	"os"
 	"strconv"
-	"strings"
 )
 
 func main() {
 	log.SetFlags(log.Lshortfile)
 	dump := flag.Bool("dump", false, "dump a graph of the computation in graphViz format")
+	file := flag.String("prog", "input.txt", "file containing the ALU program")
+	format := flag.Bool("format", false, "print the program in canonical format")
 	flag.Parse()
 
-	f, err := os.Open("input.txt")
+	f, err := os.Open(*file)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer f.Close()
 
-	prog, err := read(f)
+	prog, err := parse(f)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("%s:%v", *file, err)
+	}
+	if *format {
+		if err := prog.format(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	g := flowGraph(prog)
+	g := flowGraph(prog.insts)
 	g.optimize()
 	if *dump {
 		g.dump()
@@ -43,7 +49,7 @@ func main() {
 			continue
 		}
 
-		v := g.vars[varZ].eval(s)
+		v := g.vars[varZ].eval(s, make(map[node]int))
 		var vec []int
 		for v > 0 {
 			vec = append(vec, v%26)
@@ -57,44 +63,6 @@ func main() {
 	}
 }
 
-func read(r io.Reader) ([]instruction, error) {
-	var out []instruction
-	s := bufio.NewScanner(r)
-	for s.Scan() {
-		f := strings.Fields(s.Text())
-		var i instruction
-		switch f[0] {
-		case "inp":
-			i.op = opInp
-		case "add":
-			i.op = opAdd
-		case "mul":
-			i.op = opMul
-		case "div":
-			i.op = opDiv
-		case "mod":
-			i.op = opMod
-		case "eql":
-			i.op = opEql
-		default:
-			return nil, fmt.Errorf("unknown instruction %q", f[0])
-		}
-		var err error
-		i.arg1, err = parseVar(f[1])
-		if err != nil {
-			return nil, err
-		}
-		if i.op != opInp {
-			i.arg2, err = parseArg(f[2])
-			if err != nil {
-				return nil, err
-			}
-		}
-		out = append(out, i)
-	}
-	return out, s.Err()
-}
-
 type instruction struct {
 	op   op
 	arg1 _var
@@ -261,7 +229,7 @@ func flowGraph(prog []instruction) *graph {
 			} else {
 				arg = g.vars[inst.arg2.(_var)]
 			}
-			n = &opNode{inst.op, g.vars[inst.arg1], arg}
+			n = &opNode{o: inst.op, left: g.vars[inst.arg1], right: arg}
 		}
 		g.vars[inst.arg1] = n
 	}
@@ -448,7 +416,10 @@ func (o *optimizer) optimize(n node) node {
 
 type node interface {
 	fmt.Stringer
-	eval(string) int
+	// eval evaluates the node for the given input. Nodes are shared, so
+	// without memoization, evaluating a graph takes exponential time. memo
+	// holds the results of the nodes evaluated so far for the same input.
+	eval(in string, memo map[node]int) int
 	kind() kind
 	min() int
 	max() int
@@ -466,7 +437,7 @@ func (n *constNode) String() string {
 	return fmt.Sprintf("const(%d)", int(*n))
 }
 
-func (n *constNode) eval(_ string) int {
+func (n *constNode) eval(_ string, _ map[node]int) int {
 	return int(*n)
 }
 
@@ -500,7 +471,7 @@ func (n *inputNode) String() string {
 	return fmt.Sprintf("input[%d]", int(*n))
 }
 
-func (n *inputNode) eval(s string) int {
+func (n *inputNode) eval(s string, _ map[node]int) int {
 	if s[*n] < '0' || s[*n] > '9' {
 		panic(fmt.Sprintf("invalid byte %q in input", s[*n]))
 	}
@@ -537,8 +508,13 @@ func (n opNode) String() string {
 	return fmt.Sprintf("(%T %v %T)", n.left, n.o, n.right)
 }
 
-func (n *opNode) eval(s string) int {
-	return n.o.eval(n.left.eval(s), n.right.eval(s))
+func (n *opNode) eval(s string, memo map[node]int) int {
+	if v, ok := memo[n]; ok {
+		return v
+	}
+	v := n.o.eval(n.left.eval(s, memo), n.right.eval(s, memo))
+	memo[n] = v
+	return v
 }
 
 func (n *opNode) kind() kind {
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Merovius/aoc_2021/day24/alutest"
+)
+
+func TestGraphEval(t *testing.T) {
+	f, err := os.Open("input.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := alutest.Read(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := read(strings.NewReader(strings.Join(src, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graphEval := func(optimize bool) func(n int) alutest.Func {
+		return func(n int) alutest.Func {
+			g := flowGraph(prog[:n])
+			if optimize {
+				g.optimize()
+			}
+			return func(in []int) alutest.Regs {
+				s := make([]byte, len(in))
+				for i, v := range in {
+					s[i] = byte(v) + '0'
+				}
+				var r alutest.Regs
+				memo := make(map[node]int)
+				for i, n := range g.vars {
+					r[i] = n.eval(string(s), memo)
+				}
+				return r
+			}
+		}
+	}
+	alutest.Check(t, src, alutest.Serials(nInputs, 100, 1),
+		alutest.Impl{Name: "graph", Eval: graphEval(false), Prefixes: true},
+		alutest.Impl{Name: "optimized graph", Eval: graphEval(true), Prefixes: true},
+	)
+}
+
+func TestParse(t *testing.T) {
+	src := `# Annotated program
+mul z 0 # z = 0
+
+first:
+	inp w
+	add z w   # z += w
+second:
+	inp x
+	eql x w
+`
+	p, err := parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse(…) = _, %v, want <nil>", err)
+	}
+	want := []instruction{
+		{opMul, varZ, 0},
+		{opInp, varW, nil},
+		{opAdd, varZ, varW},
+		{opInp, varX, nil},
+		{opEql, varX, varW},
+	}
+	if !reflect.DeepEqual(p.insts, want) {
+		t.Errorf("parse(…).insts = %v, want %v", p.insts, want)
+	}
+	if want := map[int]string{1: "first", 3: "second"}; !reflect.DeepEqual(p.labels, want) {
+		t.Errorf("parse(…).labels = %v, want %v", p.labels, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tcs := []struct {
+		input string
+		want  string
+	}{
+		{"inp w\nfoo x 1", "2:1: unknown instruction \"foo\""},
+		{"inp w\n  add x", "2:8: add needs 2 arguments, got 1"},
+		{"inp w x", "1:7: unexpected \"x\" after inp"},
+		{"add q 1", "1:5: unknown var \"q\""},
+		{"add x q", "1:7: invalid argument \"q\""},
+		{"a:\nadd x 1", "2:1: label \"a\" must be followed by inp, not add"},
+		{"a:\n  b:\ninp w", "2:3: label \"b\" follows label \"a\""},
+		{"a:\ninp w\na:\ninp x", "3:1: label \"a\" already defined on line 1"},
+		{"inp w\na: # comment\n", "2:1: label \"a\" at end of program"},
+		{":\ninp w", "1:1: empty label"},
+	}
+	for _, tc := range tcs {
+		_, err := parse(strings.NewReader(tc.input))
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("parse(%q) = _, %v, want %q", tc.input, err, tc.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	src := `mul z 0
+push:
+	inp w
+	add z w
+	inp x
+	eql x w
+block1:
+	inp y
+`
+	p, err := parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parse(…) = _, %v, want <nil>", err)
+	}
+	buf := new(strings.Builder)
+	if err := p.format(buf); err != nil {
+		t.Fatalf("format(…) = %v, want <nil>", err)
+	}
+	want := "\tmul z 0\n\npush:\n\tinp w\n\tadd z w\n\nblock1.1:\n\tinp x\n\teql x w\n\nblock1:\n\tinp y\n"
+	if got := buf.String(); got != want {
+		t.Errorf("format(…) wrote\n%s\nwant\n%s", got, want)
+	}
+	p2, err := parse(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("parse(format(…)) = _, %v, want <nil>", err)
+	}
+	if !reflect.DeepEqual(p2.insts, p.insts) {
+		t.Errorf("parse(format(p)).insts = %v, want %v", p2.insts, p.insts)
+	}
+}
@@ -1,16 +1,23 @@
 package priority_queue
 
 import (
-	"constraints"
 	"container/heap"
 )
 
+// Ordered is the set of types supporting the < operator.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		~string
+}
+
 type Q[T any] struct {
 	less func(T, T) bool
 	els  []T
 }
 
-func New[T constraints.Ordered]() *Q[T] {
+func New[T Ordered]() *Q[T] {
 	return NewFunc(func(a, b T) bool { return a < b })
 }
 
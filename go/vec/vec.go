@@ -37,6 +37,17 @@ func DistL1(v, w V) int {
 	return abs(v.X-w.X) + abs(v.Y-w.Y) + abs(v.Z-w.Z)
 }
 
+func DistLInf(v, w V) int {
+	d := abs(v.X - w.X)
+	if y := abs(v.Y - w.Y); y > d {
+		d = y
+	}
+	if z := abs(v.Z - w.Z); z > d {
+		d = z
+	}
+	return d
+}
+
 type M struct {
 	A11, A12, A13 int
 	A21, A22, A23 int
@@ -72,3 +83,65 @@ func (m M) String() string {
 		"[" + strconv.Itoa(m.A21) + "," + strconv.Itoa(m.A22) + "," + strconv.Itoa(m.A23) + "]," +
 		"[" + strconv.Itoa(m.A31) + "," + strconv.Itoa(m.A32) + "," + strconv.Itoa(m.A33) + "]]"
 }
+
+// Det returns the determinant of m.
+func (m M) Det() int {
+	return m.A11*(m.A22*m.A33-m.A23*m.A32) -
+		m.A12*(m.A21*m.A33-m.A23*m.A31) +
+		m.A13*(m.A21*m.A32-m.A22*m.A31)
+}
+
+// Transpose returns the transpose of m.
+func (m M) Transpose() M {
+	return M{
+		m.A11, m.A21, m.A31,
+		m.A12, m.A22, m.A32,
+		m.A13, m.A23, m.A33,
+	}
+}
+
+// IsOrthogonal returns whether m is orthogonal, that is whether its transpose
+// is its inverse.
+func (m M) IsOrthogonal() bool {
+	return m.MulM(m.Transpose()) == ID()
+}
+
+// Inverse returns the inverse of the orthogonal matrix m. It panics, if m is
+// not orthogonal.
+func (m M) Inverse() M {
+	if !m.IsOrthogonal() {
+		panic("vec: Inverse of non-orthogonal matrix")
+	}
+	return m.Transpose()
+}
+
+// IsRotation returns whether m is a rotation, that is whether it is orthogonal
+// and has determinant 1.
+func (m M) IsRotation() bool {
+	return m.IsOrthogonal() && m.Det() == 1
+}
+
+// Rotations returns the 24 rotations which map the coordinate axes onto
+// coordinate axes. These are the signed permutation matrices with determinant
+// 1.
+func Rotations() []M {
+	perms := [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+	var out []M
+	for _, p := range perms {
+		for signs := 0; signs < 8; signs++ {
+			var rows [3][3]int
+			for i := range rows {
+				rows[i][p[i]] = 1 - 2*(signs>>i&1)
+			}
+			m := Mat(
+				rows[0][0], rows[0][1], rows[0][2],
+				rows[1][0], rows[1][1], rows[1][2],
+				rows[2][0], rows[2][1], rows[2][2],
+			)
+			if m.Det() == 1 {
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package vec
+
+import "testing"
+
+func TestDist(t *testing.T) {
+	tcs := []struct {
+		v, w    V
+		l1, inf int
+	}{
+		{Vec(0, 0, 0), Vec(0, 0, 0), 0, 0},
+		{Vec(1, 2, 3), Vec(0, 0, 0), 6, 3},
+		{Vec(1, -5, 3), Vec(-1, 0, 2), 8, 5},
+	}
+	for _, tc := range tcs {
+		if got := DistL1(tc.v, tc.w); got != tc.l1 {
+			t.Errorf("DistL1(%v, %v) = %d, want %d", tc.v, tc.w, got, tc.l1)
+		}
+		if got := DistLInf(tc.v, tc.w); got != tc.inf {
+			t.Errorf("DistLInf(%v, %v) = %d, want %d", tc.v, tc.w, got, tc.inf)
+		}
+	}
+}
+
+func TestDet(t *testing.T) {
+	tcs := []struct {
+		m    M
+		want int
+	}{
+		{ID(), 1},
+		{Mat(2, 0, 0, 0, 3, 0, 0, 0, 4), 24},
+		{Mat(0, 1, 0, 1, 0, 0, 0, 0, 1), -1},
+		{Mat(1, 2, 3, 4, 5, 6, 7, 8, 9), 0},
+		{Mat(2, -3, 1, 2, 0, -1, 1, 4, 5), 49},
+	}
+	for _, tc := range tcs {
+		if got := tc.m.Det(); got != tc.want {
+			t.Errorf("%v.Det() = %d, want %d", tc.m, got, tc.want)
+		}
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	m := Mat(1, 2, 3, 4, 5, 6, 7, 8, 9)
+	if got, want := m.Transpose(), Mat(1, 4, 7, 2, 5, 8, 3, 6, 9); got != want {
+		t.Errorf("%v.Transpose() = %v, want %v", m, got, want)
+	}
+	if got := m.Transpose().Transpose(); got != m {
+		t.Errorf("%v.Transpose().Transpose() = %v", m, got)
+	}
+}
+
+func TestRotations(t *testing.T) {
+	rs := Rotations()
+	if len(rs) != 24 {
+		t.Fatalf("len(Rotations()) = %d, want 24", len(rs))
+	}
+	seen := make(map[M]bool)
+	for _, r := range rs {
+		if seen[r] {
+			t.Errorf("Rotations() contains %v twice", r)
+		}
+		seen[r] = true
+		if !r.IsRotation() {
+			t.Errorf("IsRotation(%v) = false, want true", r)
+		}
+		if got := r.MulM(r.Inverse()); got != ID() {
+			t.Errorf("%v•%v.Inverse() = %v, want %v", r, r, got, ID())
+		}
+	}
+	// The rotations form a group.
+	for _, r := range rs {
+		if !seen[r.Inverse()] {
+			t.Errorf("Rotations() does not contain the inverse of %v", r)
+		}
+		for _, s := range rs {
+			if !seen[r.MulM(s)] {
+				t.Errorf("Rotations() does not contain %v•%v", r, s)
+			}
+		}
+	}
+}
+
+func TestIsRotation(t *testing.T) {
+	tcs := []struct {
+		m    M
+		want bool
+	}{
+		{ID(), true},
+		{Mat(-1, 0, 0, 0, -1, 0, 0, 0, -1), false},
+		{Mat(0, 1, 0, 1, 0, 0, 0, 0, 1), false},
+		{Mat(2, 0, 0, 0, 1, 0, 0, 0, 1), false},
+		{Mat(1, 1, 0, 0, 1, 0, 0, 0, 1), false},
+		{Mat(0, 0, 1, 1, 0, 0, 0, 1, 0), true},
+	}
+	for _, tc := range tcs {
+		if got := tc.m.IsRotation(); got != tc.want {
+			t.Errorf("%v.IsRotation() = %v, want %v", tc.m, got, tc.want)
+		}
+	}
+}
+
+func TestInversePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Inverse of non-orthogonal matrix did not panic")
+		}
+	}()
+	Mat(1, 1, 0, 0, 1, 0, 0, 0, 1).Inverse()
+}